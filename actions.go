@@ -0,0 +1,149 @@
+package grontab
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the environment variables describing the run of a job to its follow-up actions
+const (
+	actionJobEnv      = "GRONTAB_JOB_ID"
+	actionRunEnv      = "GRONTAB_RUN_ID"
+	actionStatusEnv   = "GRONTAB_RUN_STATUS"
+	actionExitCodeEnv = "GRONTAB_EXIT_CODE"
+	actionOutputEnv   = "GRONTAB_RUN_OUTPUT"
+	actionErrorEnv    = "GRONTAB_RUN_ERROR"
+)
+
+// the bytes of the output of a run given to its follow-up actions, the last ones are kept
+const maxActionOutput = 32 << 10
+
+// Action defines a follow-up action of a job, either another job (JobID) or an inline command (Command),
+// run after the task with the run described in the GRONTAB_JOB_ID, GRONTAB_RUN_ID, GRONTAB_RUN_STATUS,
+// GRONTAB_EXIT_CODE, GRONTAB_RUN_OUTPUT and GRONTAB_RUN_ERROR environment variables
+type Action struct {
+	JobID   string
+	Command string
+}
+
+// runJob executes a job if the system isn't under pressure, its precondition holds and its locks are free,
+// keeps track of the run in the history and runs its follow-up actions
+func runJob(job Job) Run {
+	return runJobContext(context.Background(), job)
+}
+
+// runJobContext is runJob with the task and the actions killed when ctx is done
+func runJobContext(ctx context.Context, job Job) Run {
+	waited, reason := job.Guard.wait(ctx, job)
+	if reason == "" {
+		reason = job.Precondition.check(ctx)
+	}
+	var held *heldLocks
+	if reason == "" {
+		held, reason = acquireLocks(ctx, job)
+	}
+	if reason == "" {
+		// the locks are held by the task and the follow-up actions
+		defer held.release()
+
+		// wait for a slot of the engine-wide pool, held by the task and the follow-up actions
+		if err := pool.acquire(ctx, job.Priority); err != nil {
+			reason = "pool slot not taken: " + err.Error()
+		} else {
+			defer pool.release()
+		}
+	}
+	if reason != "" {
+		log.Printf(yellow("SKIP JOB : {%s %s}: %s"), job.ID, job.Task, reason)
+		run := skippedRun(job, time.Now(), reason)
+		recordRun(run)
+		return run
+	}
+
+	run := executeContext(ctx, job)
+	if waited > 0 {
+		run.Reason = "deferred " + waited.Round(time.Second).String() + " by the guard"
+	}
+	recordRun(run)
+	countRun(job.ID)
+	runActions(ctx, job, run)
+	return run
+}
+
+// validateActions checks that the actions of a job are either a job or a command
+func validateActions(job Job) error {
+	for _, actions := range [][]Action{job.OnSuccess, job.OnFailure, job.OnTimeout} {
+		for _, action := range actions {
+			if (action.JobID == "") == (strings.TrimSpace(action.Command) == "") {
+				return errors.New("an action must have either a JobID or a Command")
+			}
+		}
+	}
+	return nil
+}
+
+// followUps returns the actions following a run, the timed out runs without OnTimeout actions
+// are followed by the OnFailure ones
+func followUps(job Job, run Run) []Action {
+	switch {
+	case run.TimedOut && len(job.OnTimeout) > 0:
+		return job.OnTimeout
+	case run.Status == RunFailed:
+		return job.OnFailure
+	case run.Status == RunSucceeded:
+		return job.OnSuccess
+	}
+	return nil
+}
+
+// runActions runs the follow-up actions of a run, one after the other,
+// the runs of the referenced jobs are recorded in their history but don't trigger their own actions
+func runActions(ctx context.Context, job Job, run Run) {
+	actions := followUps(job, run)
+	if len(actions) == 0 {
+		return
+	}
+
+	output := run.Output
+	if len(output) > maxActionOutput {
+		output = output[len(output)-maxActionOutput:]
+	}
+	env := []string{
+		actionJobEnv + "=" + job.ID,
+		actionRunEnv + "=" + run.ID,
+		actionStatusEnv + "=" + string(run.Status),
+		actionExitCodeEnv + "=" + strconv.Itoa(run.ExitCode),
+		actionOutputEnv + "=" + output,
+		actionErrorEnv + "=" + run.Error,
+	}
+
+	for _, action := range actions {
+		if action.Command != "" {
+			actionRun := executeContext(ctx, Job{ID: job.ID, Task: action.Command}, env...)
+			log.Printf(cyan("ACTN [%s][%s]: %s --> %s"), job.ID, action.Command, actionRun.Status,
+				strings.Replace(actionRun.Output, "\n", "", -1))
+			continue
+		}
+
+		var target Job
+		err := store.View(func(tx Tx) error {
+			var err error
+			target, err = tx.GetJob(action.JobID)
+			return err
+		})
+		if err != nil {
+			log.Printf(red("Error getting the job %s following job %s: %s"), action.JobID, job.ID, err)
+			continue
+		}
+		actionRun := executeContext(ctx, target, env...)
+		recordRun(actionRun)
+		countRun(target.ID)
+		log.Printf(cyan("ACTN [%s][%s]: %s --> %s"), job.ID, target.ID, actionRun.Status,
+			strings.Replace(actionRun.Output, "\n", "", -1))
+	}
+}
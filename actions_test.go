@@ -0,0 +1,76 @@
+package grontab
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowUpActions(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	if _, err := Add("0 0 0 1 1 *", Job{Task: "true", OnFailure: []Action{{}}}); err == nil {
+		t.Errorf("expected Add() to refuse an action without a job or a command")
+	}
+
+	dir := t.TempDir()
+	cleanup := writeTestScript(t, "cleanup.sh", `echo "$GRONTAB_JOB_ID $GRONTAB_RUN_STATUS $GRONTAB_EXIT_CODE $GRONTAB_RUN_OUTPUT" > `+dir+`/cleanup`)
+	marker := writeTestScript(t, "marker.sh", `touch `+dir+`/marker`)
+	failing := writeTestScript(t, "failing.sh", "echo 'disk full'\nexit 3")
+
+	jobs := []Job{
+		{ID: "cleanup", Task: cleanup},
+		{ID: "failing", Task: failing, OnFailure: []Action{{JobID: "cleanup"}}, OnSuccess: []Action{{Command: marker}}},
+		{ID: "passing", Task: "true", OnSuccess: []Action{{Command: marker}}, OnFailure: []Action{{JobID: "cleanup"}}},
+		{ID: "slow", Task: "sleep 5", Timeout: 100 * time.Millisecond, OnTimeout: []Action{{Command: "touch " + dir + "/timeout"}}},
+	}
+	for _, job := range jobs {
+		if _, err := Add("0 0 0 1 1 *", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the failure runs the cleanup job, with the failed run in its environment
+	job, _ := getTestJob("failing")
+	run := runJob(job)
+	if run.Status != RunFailed || run.ExitCode != 3 {
+		t.Errorf("expected the run to fail with exit code 3, got %+v", run)
+	}
+	content, err := ioutil.ReadFile(dir + "/cleanup")
+	if err != nil || strings.TrimSpace(string(content)) != "failing failed 3 disk full" {
+		t.Errorf("expected the cleanup to get the failed run, got '%s' (%v)", content, err)
+	}
+	if runs, _ := History("cleanup"); len(runs) != 1 {
+		t.Errorf("expected the run of the cleanup job in its history, got %v", runs)
+	}
+	if _, err := os.Stat(dir + "/marker"); err == nil {
+		t.Errorf("expected the OnSuccess action not to run after a failure")
+	}
+
+	job, _ = getTestJob("passing")
+	runJob(job)
+	if _, err := os.Stat(dir + "/marker"); err != nil {
+		t.Errorf("expected the OnSuccess command to run, got %s", err)
+	}
+	if runs, _ := History("cleanup"); len(runs) != 1 {
+		t.Errorf("expected the OnFailure action not to run after a success, got %v", runs)
+	}
+
+	job, _ = getTestJob("slow")
+	start := time.Now()
+	run = runJob(job)
+	if !run.TimedOut || run.Status != RunFailed || time.Since(start) > 3*time.Second {
+		t.Errorf("expected the run to time out, got %+v", run)
+	}
+	if _, err := os.Stat(dir + "/timeout"); err != nil {
+		t.Errorf("expected the OnTimeout command to run, got %s", err)
+	}
+}
@@ -0,0 +1,412 @@
+package grontab
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// the layout of the days of a calendar
+const calendarDay = "2006-01-02"
+
+// Calendar defines a named set of days in a time zone, like the public holidays or a change freeze,
+// that jobs reference to run only on its days (IncludeCalendars) or to skip them (ExcludeCalendars)
+type Calendar struct {
+	Name string `storm:"id"`
+	// TimeZone is an IANA name like "Europe/Rome", the local one if empty
+	TimeZone string
+	// Dates are days like "2026-12-25" or ranges of days like "2026-12-20/2027-01-06"
+	Dates []string
+	// Rules are recurring days, as recurrence sets like "DTSTART;VALUE=DATE:20261225 RRULE:FREQ=YEARLY"
+	Rules []string
+}
+
+// AddCalendar adds a calendar to the storage, replacing the one with the same name
+func AddCalendar(calendar Calendar) error {
+	return addCalendar(calendar)
+}
+
+// RemoveCalendar removes a calendar not referenced by any job
+func RemoveCalendar(name string) error {
+	return removeCalendar(name)
+}
+
+// Calendars returns the calendars in the storage
+func Calendars() ([]Calendar, error) {
+	return calendars()
+}
+
+// ParseICS reads the events of an iCalendar (.ics) file into a calendar holding their days,
+// the recurring events are kept as rules, the times are read in the tz time zone (the local one if empty)
+func ParseICS(name string, tz string, r io.Reader) (Calendar, error) {
+	return parseICS(name, tz, r)
+}
+
+func addCalendar(calendar Calendar) error {
+	err := calendar.validate()
+	if err != nil {
+		return errors.Wrap(err, "Error Adding calendar "+calendar.Name)
+	}
+	err = store.Update(func(tx Tx) error {
+		return tx.PutCalendar(calendar)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error Adding calendar "+calendar.Name)
+	}
+	return nil
+}
+
+func removeCalendar(name string) error {
+	err := store.Update(func(tx Tx) error {
+		jobs, err := tx.Jobs()
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if containsString(job.IncludeCalendars, name) || containsString(job.ExcludeCalendars, name) {
+				return errors.Errorf("the calendar is referenced by job %s", job.ID)
+			}
+		}
+		return tx.DeleteCalendar(name)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to Remove calendar "+name)
+	}
+	return nil
+}
+
+func calendars() ([]Calendar, error) {
+	var calendars []Calendar
+	err := store.View(func(tx Tx) error {
+		var err error
+		calendars, err = tx.Calendars()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Getting calendars")
+	}
+	return calendars, nil
+}
+
+// validate checks the name, the time zone, the dates and the rules of a calendar
+func (cal Calendar) validate() error {
+	if cal.Name == "" {
+		return errors.New("a calendar needs a name")
+	}
+	if _, err := cal.location(); err != nil {
+		return errors.Wrap(err, "invalid time zone")
+	}
+	for _, date := range cal.Dates {
+		days := strings.SplitN(date, "/", 2)
+		for _, day := range days {
+			if _, err := time.Parse(calendarDay, day); err != nil {
+				return errors.Errorf("invalid date '%s', dates must be like 2026-12-25 or 2026-12-20/2027-01-06", date)
+			}
+		}
+		if len(days) == 2 && days[0] > days[1] {
+			return errors.Errorf("the range of days '%s' begins after its end", date)
+		}
+	}
+	for _, rule := range cal.Rules {
+		if _, err := parseRuleSet(normalizeSchedule(rule)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// location returns the time zone of the calendar
+func (cal Calendar) location() (*time.Location, error) {
+	if cal.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(cal.TimeZone)
+}
+
+// contains reports if the day of t, in the time zone of the calendar, is one of its days,
+// and returns the end of that day
+func (cal Calendar) contains(t time.Time) (bool, time.Time) {
+	loc, err := cal.location()
+	if err != nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+
+	day := t.Format(calendarDay)
+	for _, date := range cal.Dates {
+		days := strings.SplitN(date, "/", 2)
+		if day == days[0] || len(days) == 2 && day >= days[0] && day <= days[1] {
+			return true, end
+		}
+	}
+	for _, rule := range cal.Rules {
+		s, err := parseRuleSet(normalizeSchedule(rule))
+		if err != nil {
+			continue
+		}
+		if next := s.Next(start.Add(-time.Second)); !next.IsZero() && next.Before(end) {
+			return true, end
+		}
+	}
+	return false, end
+}
+
+// calendarSkip returns why an activation at t is skipped by the calendars of a job,
+// empty if it isn't, and when the skipped day ends
+func calendarSkip(t time.Time, include []Calendar, exclude []Calendar) (string, time.Time) {
+	for _, cal := range exclude {
+		if ok, end := cal.contains(t); ok {
+			return "the day is in the excluded calendar " + cal.Name, end
+		}
+	}
+	if len(include) == 0 {
+		return "", time.Time{}
+	}
+
+	var names []string
+	var until time.Time
+	for _, cal := range include {
+		ok, end := cal.contains(t)
+		if ok {
+			return "", time.Time{}
+		}
+		if until.IsZero() || end.Before(until) {
+			until = end
+		}
+		names = append(names, cal.Name)
+	}
+	return "the day is not in the calendars " + strings.Join(names, ", "), until
+}
+
+// skipReason returns why the activation of a job at t is skipped by its calendars, empty if it isn't
+func skipReason(job Job, t time.Time) string {
+	if len(job.IncludeCalendars)+len(job.ExcludeCalendars) == 0 {
+		return ""
+	}
+	var include, exclude []Calendar
+	err := store.View(func(tx Tx) error {
+		var err error
+		include, exclude, err = jobCalendars(tx, job)
+		return err
+	})
+	if err != nil {
+		return err.Error()
+	}
+	reason, _ := calendarSkip(t, include, exclude)
+	return reason
+}
+
+// jobCalendars returns the calendars included and excluded by a job
+func jobCalendars(tx Tx, job Job) ([]Calendar, []Calendar, error) {
+	var include, exclude []Calendar
+	for _, name := range job.IncludeCalendars {
+		cal, err := tx.GetCalendar(name)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Error Getting calendar "+name)
+		}
+		include = append(include, cal)
+	}
+	for _, name := range job.ExcludeCalendars {
+		cal, err := tx.GetCalendar(name)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Error Getting calendar "+name)
+		}
+		exclude = append(exclude, cal)
+	}
+	return include, exclude, nil
+}
+
+// calendarSchedule skips the activations of a schedule on the days excluded by calendars
+type calendarSchedule struct {
+	schedule cron.Schedule
+	include  []Calendar
+	exclude  []Calendar
+}
+
+// Next returns the first activation after t not skipped by the calendars
+func (s calendarSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxCompositeSteps; i++ {
+		next := s.schedule.Next(t)
+		if next.IsZero() {
+			return next
+		}
+		reason, until := calendarSkip(next, s.include, s.exclude)
+		if reason == "" {
+			return next
+		}
+		// the whole day is skipped
+		t = until.Add(-time.Second)
+		if t.Before(next) {
+			t = next
+		}
+	}
+	return time.Time{}
+}
+
+// jobRunSchedule returns the schedule a job runs at, within its validity window
+// and skipping the days excluded by its calendars
+func jobRunSchedule(tx Tx, job Job) (cron.Schedule, error) {
+	schedule, err := parseSchedule(jobSchedule(job))
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := schedule.(delaySchedule); ok {
+		s.next = job.DelayedNext
+		schedule = s
+	}
+	if !job.NotBefore.IsZero() || !job.NotAfter.IsZero() {
+		schedule = windowSchedule{schedule: schedule, notBefore: job.NotBefore, notAfter: job.NotAfter}
+	}
+	if len(job.IncludeCalendars)+len(job.ExcludeCalendars) == 0 {
+		return schedule, nil
+	}
+	include, exclude, err := jobCalendars(tx, job)
+	if err != nil {
+		return nil, err
+	}
+	return calendarSchedule{schedule: schedule, include: include, exclude: exclude}, nil
+}
+
+func parseICS(name string, tz string, r io.Reader) (Calendar, error) {
+	cal := Calendar{Name: name, TimeZone: tz}
+	loc, err := cal.location()
+	if err != nil {
+		return cal, errors.Wrap(err, "Error Parsing calendar "+name)
+	}
+
+	// unfold the content lines continued on the following lines
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return cal, errors.Wrap(err, "Error Parsing calendar "+name)
+	}
+
+	var event map[string]string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = make(map[string]string)
+		case line == "END:VEVENT":
+			if event != nil {
+				if err := cal.addEvent(event, loc); err != nil {
+					return cal, errors.Wrap(err, "Error Parsing calendar "+name)
+				}
+			}
+			event = nil
+		case event != nil:
+			property := line
+			if i := strings.IndexAny(line, ";:"); i >= 0 {
+				property = line[:i]
+			}
+			switch property = strings.ToUpper(property); property {
+			case "DTSTART", "DTEND", "RRULE", "EXDATE":
+				// the content line as a whole, for the EXDATEs all of them
+				if event[property] != "" {
+					event[property] += " "
+				}
+				event[property] += line
+			}
+		}
+	}
+	return cal, nil
+}
+
+// addEvent adds the days of an event, its content lines by property, to the calendar
+func (cal *Calendar) addEvent(event map[string]string, loc *time.Location) error {
+	if event["DTSTART"] == "" {
+		return errors.New("an event has no DTSTART")
+	}
+	start, err := eventDay(event["DTSTART"], loc, false)
+	if err != nil {
+		return err
+	}
+
+	if event["RRULE"] != "" {
+		// the recurring events are kept as rules on their first day
+		rule := "DTSTART;TZID=" + loc.String() + ";VALUE=DATE:" + start.Format(icalDate) + " " + event["RRULE"]
+		if event["EXDATE"] != "" {
+			for _, line := range strings.Fields(event["EXDATE"]) {
+				_, params, value, err := splitContentLine(line)
+				if err != nil {
+					return err
+				}
+				times, _, err := parseDateTimes("EXDATE", params, value)
+				if err != nil {
+					return err
+				}
+				var days []string
+				for _, t := range times {
+					days = append(days, t.In(loc).Format(icalDate))
+				}
+				rule += " EXDATE;TZID=" + loc.String() + ";VALUE=DATE:" + strings.Join(days, ",")
+			}
+		}
+		if _, err := parseRuleSet(rule); err != nil {
+			return err
+		}
+		cal.Rules = append(cal.Rules, rule)
+		return nil
+	}
+
+	end := start
+	if event["DTEND"] != "" {
+		if end, err = eventDay(event["DTEND"], loc, true); err != nil {
+			return err
+		}
+		if end.Before(start) {
+			end = start
+		}
+	}
+	if end.Equal(start) {
+		cal.Dates = append(cal.Dates, start.Format(calendarDay))
+	} else {
+		cal.Dates = append(cal.Dates, start.Format(calendarDay)+"/"+end.Format(calendarDay))
+	}
+	return nil
+}
+
+// eventDay returns the day of a DTSTART or a DTEND content line in loc,
+// the end of an event is exclusive: the day before a date, or before a date-time at midnight
+func eventDay(line string, loc *time.Location, end bool) (time.Time, error) {
+	name, params, value, err := splitContentLine(line)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if _, ok := params["TZID"]; !ok {
+		params["TZID"] = loc.String()
+	}
+	times, dates, err := parseDateTimes(name, params, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := times[0].In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if end && (dates || t.Equal(day)) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day, nil
+}
+
+// containsString reports if values contains v
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
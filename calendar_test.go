@@ -0,0 +1,152 @@
+package grontab
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testICS = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+SUMMARY:Christmas
+DTSTART;VALUE=DATE:20261225
+RRULE:FREQ=YEARLY
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Change freeze
+DTSTART;VALUE=DATE:20261228
+DTEND;VALUE=DATE:20270102
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Maintenance
+DTSTART:20261015T220000Z
+DTEND:20261015T230000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParseICS(t *testing.T) {
+	cal, err := ParseICS("holidays", "Europe/Rome", strings.NewReader(testICS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cal.Dates, " ") != "2026-12-28/2027-01-01 2026-10-16" {
+		t.Errorf("expected the days of the events, got %v", cal.Dates)
+	}
+	if len(cal.Rules) != 1 {
+		t.Fatalf("expected the recurring event as a rule, got %v", cal.Rules)
+	}
+	if err := cal.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rome, _ := time.LoadLocation("Europe/Rome")
+	for day, expected := range map[string]bool{
+		"2026-12-25": true, "2027-12-25": true, "2026-12-24": false,
+		"2026-12-31": true, "2027-01-02": false, "2026-10-16": true,
+	} {
+		at, _ := time.ParseInLocation(calendarDay, day, rome)
+		if ok, _ := cal.contains(at.Add(9 * time.Hour)); ok != expected {
+			t.Errorf("expected the calendar to contain %s: %t", day, expected)
+		}
+	}
+}
+
+func TestCalendarSchedule(t *testing.T) {
+	holidays := Calendar{Name: "holidays", TimeZone: "UTC", Dates: []string{"2026-12-25", "2026-12-31/2027-01-01"}}
+	schedule, _ := parseSchedule("0 0 9 * * 1-5")
+
+	from := time.Date(2026, time.December, 24, 12, 0, 0, 0, time.UTC)
+	times := activations(calendarSchedule{schedule: schedule, exclude: []Calendar{holidays}}, from, 5)
+	var days []string
+	for _, at := range times {
+		days = append(days, at.Format(calendarDay))
+	}
+	if strings.Join(days, " ") != "2026-12-28 2026-12-29 2026-12-30 2027-01-04 2027-01-05" {
+		t.Errorf("expected the holidays to be skipped, got %v", days)
+	}
+
+	times = activations(calendarSchedule{schedule: schedule, include: []Calendar{holidays}}, from, 3)
+	days = nil
+	for _, at := range times {
+		days = append(days, at.Format(calendarDay))
+	}
+	if strings.Join(days, " ") != "2026-12-25 2026-12-31 2027-01-01" {
+		t.Errorf("expected only the days of the calendar, got %v", days)
+	}
+
+	reason, _ := calendarSkip(time.Date(2026, time.December, 25, 9, 0, 0, 0, time.UTC), nil, []Calendar{holidays})
+	if reason != "the day is in the excluded calendar holidays" {
+		t.Errorf("unexpected reason '%s'", reason)
+	}
+
+	for _, cal := range []Calendar{{}, {Name: "x", TimeZone: "Mars/Olympus"}, {Name: "x", Dates: []string{"25/12/2026"}},
+		{Name: "x", Dates: []string{"2027-01-01/2026-12-31"}}, {Name: "x", Rules: []string{"RRULE:FREQ=YEARLY"}}} {
+		if err := cal.validate(); err == nil {
+			t.Errorf("expected the calendar %+v to be invalid", cal)
+		}
+	}
+}
+
+func TestCalendarJobs(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	_, err := Add("0 0 9 * * *", Job{ID: "report", Task: "echo 'report'", Enabled: true, ExcludeCalendars: []string{"today"}})
+	if err == nil {
+		t.Errorf("expected Add() to refuse a job referencing a missing calendar")
+	}
+
+	today := time.Now().Format(calendarDay)
+	err = AddCalendar(Calendar{Name: "today", Dates: []string{today}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Add("0 0 9 * * *", Job{ID: "report", Task: "echo 'report'", Enabled: true, ExcludeCalendars: []string{"today"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendars, err := Calendars()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendars) != 1 || calendars[0].Name != "today" {
+		t.Errorf("expected the calendar to be stored, got %v", calendars)
+	}
+
+	times, err := NextRuns("report", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range times {
+		if at.Format(calendarDay) == today {
+			t.Errorf("expected the job not to run today, got %v", times)
+		}
+	}
+
+	// an activation today is skipped and recorded
+	workerFuncGen(jobSchedule(Job{ID: "report", Schedule: "0 0 9 * * *"}))()
+	runs, err := History("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != RunSkipped || runs[0].Reason != "the day is in the excluded calendar today" {
+		t.Errorf("expected a skipped run with its reason, got %+v", runs)
+	}
+
+	if err := RemoveCalendar("today"); err == nil {
+		t.Errorf("expected RemoveCalendar() to refuse a calendar referenced by a job")
+	}
+	Remove("report")
+	if err := RemoveCalendar("today"); err != nil {
+		t.Errorf("expected RemoveCalendar() to remove an unreferenced calendar, got %v", err)
+	}
+}
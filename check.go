@@ -0,0 +1,141 @@
+package grontab
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IssueKind defines the kind of an inconsistency found by Check
+type IssueKind string
+
+// the inconsistencies detected by Check
+const (
+	IssueInvalidSchedule  IssueKind = "invalid-schedule"
+	IssueMissingCronEntry IssueKind = "missing-cron-entry"
+	IssueOrphanCronEntry  IssueKind = "orphan-cron-entry"
+)
+
+// Issue defines an inconsistency between the storage and the cron engine
+type Issue struct {
+	Kind     IssueKind
+	Schedule string
+	Detail   string
+	Repaired bool
+}
+
+// Check detects inconsistencies in the storage and in the cron engine of this process,
+// only in the storage if it is read only
+func Check() ([]Issue, error) {
+	return check()
+}
+
+// Repair detects inconsistencies and aligns the cron engine to the storage
+func Repair() ([]Issue, error) {
+	return repair()
+}
+
+func check() ([]Issue, error) {
+	var jobs []Job
+	err := store.View(func(tx Tx) error {
+		var err error
+		jobs, err = tx.Jobs()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Checking grontab")
+	}
+
+	var issues []Issue
+	registered := registeredSchedules()
+
+	// sort the jobs by schedule to make the report (and the repair) deterministic
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Schedule == jobs[j].Schedule {
+			return jobs[i].ID < jobs[j].ID
+		}
+		return jobs[i].Schedule < jobs[j].Schedule
+	})
+
+	// the schedules seen so far
+	schedules := make(map[string]bool)
+
+	for _, job := range jobs {
+		// the schedule the job runs at, with its H tokens resolved
+		gid := jobSchedule(job)
+		if !schedules[gid] {
+			schedules[gid] = true
+
+			_, parseErr := parseSchedule(job.Schedule)
+			switch {
+			case parseErr != nil:
+				issues = append(issues, Issue{Kind: IssueInvalidSchedule, Schedule: job.Schedule, Detail: parseErr.Error()})
+			case isDelay(gid):
+				// the fixed-delay jobs are run by their own timers, not by the cron engine
+			case grontabConfiguration.ReadOnly:
+				// a read only storage has no cron engine to check
+			case !registered[gid]:
+				issues = append(issues, Issue{Kind: IssueMissingCronEntry, Schedule: gid, Detail: "schedule is not registered in the cron engine"})
+			}
+		}
+	}
+
+	var entries []string
+	for gid := range registered {
+		entries = append(entries, gid)
+	}
+	sort.Strings(entries)
+
+	for _, gid := range entries {
+		if !schedules[gid] {
+			issues = append(issues, Issue{Kind: IssueOrphanCronEntry, Schedule: gid, Detail: "cron entry has no schedule in the storage"})
+		}
+	}
+
+	return issues, nil
+}
+
+func repair() ([]Issue, error) {
+	if grontabConfiguration.ReadOnly {
+		return nil, errors.New("Error Repairing grontab: the storage is read only")
+	}
+	issues, err := check()
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return issues, nil
+	}
+
+	// align the cron engine to the storage
+	groups, err := loadJobGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Repairing grontab")
+	}
+
+	for i, issue := range issues {
+		switch issue.Kind {
+		case IssueOrphanCronEntry:
+			unregisterSchedule(issue.Schedule)
+			issues[i].Repaired = true
+		case IssueMissingCronEntry:
+			if len(groups[issue.Schedule]) == 0 {
+				issues[i].Repaired = true
+				continue
+			}
+			for _, job := range groups[issue.Schedule] {
+				if err = registerSchedule(issue.Schedule, job.Schedule); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				issues[i].Detail = strings.Join([]string{issue.Detail, err.Error()}, ": ")
+				continue
+			}
+			issues[i].Repaired = true
+		}
+	}
+
+	return issues, nil
+}
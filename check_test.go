@@ -0,0 +1,128 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCheckConsistentStorage(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	_, err := Add("*/10 * * * * *", Job{Task: "echo 'ciaone'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fixed-delay jobs have no cron entry
+	_, err = Add("@delay 1h", Job{Task: "echo 'delayed'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected Check() to find no issues, found %v", issues)
+	}
+}
+
+func TestRepairInconsistentStorage(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	timing := "*/10 * * * * *"
+	_, err := Add(timing, Job{Task: "echo 'ciaone'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the storage and the cron engine behind grontab's back
+	store.Update(func(tx Tx) error {
+		tx.PutJob(Job{ID: "unregistered", Schedule: "*/20 * * * * *", Task: "echo 'ciaone'", Enabled: true})
+		return tx.PutJob(Job{ID: "invalid", Schedule: "not a schedule", Task: "echo 'ciaone'"})
+	})
+	registerSchedule("*/30 * * * * *", "*/30 * * * * *")
+
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[IssueKind]bool)
+	for _, issue := range issues {
+		found[issue.Kind] = true
+	}
+	for _, kind := range []IssueKind{IssueInvalidSchedule, IssueMissingCronEntry, IssueOrphanCronEntry} {
+		if !found[kind] {
+			t.Errorf("expected Check() to detect %s", kind)
+		}
+	}
+
+	issues, err = Repair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if !issue.Repaired && issue.Kind != IssueInvalidSchedule {
+			t.Errorf("expected Repair() to fix %v", issue)
+		}
+	}
+
+	issues, err = Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Kind != IssueInvalidSchedule {
+		t.Errorf("expected only the invalid schedule to be left after Repair(), found %v", issues)
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	if testBackend == "memory" {
+		t.Skip("the memory store doesn't persist across restarts")
+	}
+
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	_, err := Add("*/10 * * * * *", Job{ID: "stored", Task: "echo 'ciaone'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Stop()
+
+	// a read only storage has its schedules left out of the cron engine, Check doesn't report them
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true, ReadOnly: true})
+	defer Stop()
+	if registered := registeredSchedules(); len(registered) != 0 {
+		t.Errorf("expected no schedules registered, got %v", registered)
+	}
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected Check() to find no issues, found %v", issues)
+	}
+	if _, err := Repair(); err == nil {
+		t.Errorf("expected Repair() to refuse a read only storage")
+	}
+	if jobs := List(); len(jobs["*/10 * * * * *"]) != 1 {
+		t.Errorf("expected the stored job to be listed, got %v", jobs)
+	}
+}
@@ -0,0 +1,236 @@
+// Command grontab inspects and maintains a grontab persistent storage
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/damdo/grontab"
+)
+
+const usage = `usage: grontab [flags] <command>
+
+commands:
+  preview  print the next activations of a schedule, see: grontab preview -h
+  explain  describe a schedule in english
+  lint     report likely mistakes in a schedule
+  parse    convert an english phrase into a schedule
+  list     list the jobs, see: grontab list -h
+  calendar list, load or remove the calendars, see: grontab calendar -h
+  check    report the invalid schedules in the storage
+  repair   migrate the storage and report the invalid schedules left to fix by hand
+           (the cron entries are compared with the storage, and aligned to it,
+           only by Check() and Repair() in the process running grontab)
+
+flags:
+`
+
+func main() {
+	dbPath := flag.String("db", "./db.db", "path of the grontab persistent storage")
+	bucket := flag.String("bucket", "jobs", "name of the grontab bucket")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// the schedule commands don't need the storage
+	if command, ok := scheduleCommands[flag.Arg(0)]; ok {
+		os.Exit(command(flag.Args()[1:]))
+	}
+
+	err := grontab.Init(grontab.Config{
+		BucketName:      *bucket,
+		PersistencePath: *dbPath,
+		HideBanner:      true,
+		TurnOffLogs:     true,
+		ReadOnly:        readOnly(flag.Arg(0), flag.Args()[1:]),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := run(flag.Arg(0), flag.Args()[1:])
+	grontab.Stop()
+	os.Exit(code)
+}
+
+// run executes a subcommand and returns the process exit code
+func run(command string, args []string) int {
+	switch command {
+	case "list":
+		return list(args)
+
+	case "calendar":
+		return calendar(args)
+
+	case "check":
+		issues, err := grontab.Check()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printIssues(issues)
+		if len(issues) > 0 {
+			return 1
+		}
+		return 0
+
+	case "repair":
+		issues, err := grontab.Repair()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printIssues(issues)
+		for _, issue := range issues {
+			if !issue.Repaired {
+				return 1
+			}
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n", command)
+	flag.Usage()
+	return 2
+}
+
+// readOnly reports if a subcommand only reads the storage, all but repair and the calendar load and rm ones:
+// it then opens the storage without migrating it nor registering its schedules
+func readOnly(command string, args []string) bool {
+	if command == "repair" {
+		return false
+	}
+	return command != "calendar" || len(args) == 0 || args[0] == "list"
+}
+
+// list prints the jobs matching the filter given by args
+func list(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	labels := fs.String("l", "", "label selector, e.g. team=billing,env!=dev")
+	name := fs.String("name", "", "match the jobs whose name contains this")
+	schedule := fs.String("schedule", "", "match the jobs at this schedule")
+	enabled := fs.String("enabled", "", "match the enabled (true) or disabled (false) jobs")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := grontab.Filter{Labels: *labels, Name: *name, Schedule: *schedule}
+	if *enabled != "" {
+		value, err := strconv.ParseBool(*enabled)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		filter.Enabled = &value
+	}
+
+	jobs, err := grontab.ListJobs(filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SCHEDULE\tID\tNAME\tOWNER\tENABLED\tNEXT\tPREV\tTASK")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\n", job.Schedule, job.ID, job.Name, job.Owner, job.Enabled, formatTime(job.Next), formatTime(job.Prev), job.Task)
+	}
+	w.Flush()
+	return 0
+}
+
+// calendar lists the calendars, loads one from an ICS file or removes one
+func calendar(args []string) int {
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	tz := fs.String("tz", "", "time zone of a loaded calendar, e.g. Europe/Rome (default the local one)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: grontab calendar [list | load [-tz zone] <name> <file.ics> | rm <name>]")
+		fs.PrintDefaults()
+	}
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	switch {
+	case command == "list" && fs.NArg() == 0:
+		calendars, err := grontab.Calendars()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTIMEZONE\tDATES\tRULES")
+		for _, cal := range calendars {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", cal.Name, cal.TimeZone, len(cal.Dates), len(cal.Rules))
+		}
+		w.Flush()
+		return 0
+
+	case command == "load" && fs.NArg() == 2:
+		file, err := os.Open(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		cal, err := grontab.ParseICS(fs.Arg(0), *tz, file)
+		if err == nil {
+			err = grontab.AddCalendar(cal)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("loaded %d dates and %d rules into %s\n", len(cal.Dates), len(cal.Rules), cal.Name)
+		return 0
+
+	case command == "rm" && fs.NArg() == 1:
+		if err := grontab.RemoveCalendar(fs.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	fs.Usage()
+	return 2
+}
+
+// formatTime formats an activation time, a dash for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// printIssues prints one line per issue
+func printIssues(issues []grontab.Issue) {
+	if len(issues) == 0 {
+		fmt.Println("no issues found")
+		return
+	}
+	for _, issue := range issues {
+		status := ""
+		if issue.Repaired {
+			status = " (repaired)"
+		}
+		fmt.Printf("%-20s ['%s'] %s%s\n", issue.Kind, issue.Schedule, issue.Detail, status)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/damdo/grontab"
+)
+
+// scheduleCommands are the subcommands working on schedule expressions only
+var scheduleCommands = map[string]func(args []string) int{
+	"preview": preview,
+	"explain": explain,
+	"lint":    lint,
+	"parse":   parse,
+}
+
+// preview prints the next activations of the schedule expression given by args
+func preview(args []string) int {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	n := fs.Int("n", 5, "number of activations to print")
+	tz := fs.String("tz", "", "time zone of the schedule, e.g. Europe/Rome (default local)")
+	from := fs.String("from", "", "RFC3339 time to start from (default now)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: grontab preview [flags] <schedule>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+
+	start := time.Now()
+	if *from != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, *from)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	times, err := grontab.PreviewSchedule(strings.Join(fs.Args(), " "), start, *n, *tz)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, t := range times {
+		fmt.Printf("%s  (in %s)\n", t.Format(time.RFC3339), t.Sub(start).Round(time.Second))
+	}
+	return 0
+}
+
+// explain prints an english description of the schedule expression given by args
+func explain(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: grontab explain <schedule>")
+		return 2
+	}
+
+	description, err := grontab.Explain(strings.Join(args, " "))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(description)
+	return 0
+}
+
+// lint prints the likely mistakes in the schedule expression given by args,
+// it exits with 1 when there is any
+func lint(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: grontab lint <schedule>")
+		return 2
+	}
+
+	warnings, err := grontab.Lint(strings.Join(args, " "))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, w := range warnings {
+		fmt.Printf("%s: %s\n", w.Code, w.Message)
+	}
+	if len(warnings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parse prints the schedule expression of the english phrase given by args
+func parse(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: grontab parse <phrase>")
+		return 2
+	}
+
+	schedule, err := grontab.ParseNatural(strings.Join(args, " "))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(schedule)
+	return 0
+}
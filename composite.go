@@ -0,0 +1,391 @@
+package grontab
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// the maximum number of steps taken by a composite schedule looking for its next activation,
+// after them the schedule is considered unsatisfiable
+const maxCompositeSteps = 10000
+
+// compositeSchedule defines a schedule built from other schedules,
+// written as "@name(arg; arg; ...)"
+type compositeSchedule struct {
+	// parse builds the schedule from its arguments
+	parse func(args []string) (cron.Schedule, error)
+	// scheduleArg reports if the i-th argument is a schedule expression
+	scheduleArg func(i int) bool
+}
+
+// compositeSchedules are the composite schedules by name
+var compositeSchedules map[string]compositeSchedule
+
+// the composite schedules parse their arguments with parseSchedule,
+// which looks them up, so they are registered at init
+func init() {
+	compositeSchedules = map[string]compositeSchedule{
+		"intersect": {parse: parseIntersect, scheduleArg: func(i int) bool { return true }},
+		"random":    {parse: parseRandom, scheduleArg: func(i int) bool { return i == 1 }},
+		"union":     {parse: parseUnion, scheduleArg: func(i int) bool { return true }},
+		"except":    {parse: parseExcept, scheduleArg: func(i int) bool { return true }},
+		"offset":    {parse: parseOffset, scheduleArg: func(i int) bool { return i == 1 }},
+		"between":   {parse: parseBetween, scheduleArg: func(i int) bool { return i == 2 }},
+	}
+}
+
+// Union returns a schedule expression activating when any of the schedules activates
+func Union(schedules ...string) string {
+	return composite("union", schedules...)
+}
+
+// Intersect returns a schedule expression activating only when all of the schedules activate
+func Intersect(schedules ...string) string {
+	return composite("intersect", schedules...)
+}
+
+// Except returns a schedule expression activating when schedule activates but excluded doesn't,
+// e.g. Except("0 */5 * * * *", "* * 2 * * *") every 5 minutes except between 02:00 and 03:00
+func Except(schedule string, excluded string) string {
+	return composite("except", schedule, excluded)
+}
+
+// Offset returns a schedule expression activating offset after (or before, if negative) each activation of schedule
+func Offset(offset time.Duration, schedule string) string {
+	// "10m" rather than "10m0s", "1h" rather than "1h0m0s"
+	d := offset.String()
+	if strings.HasSuffix(d, "m0s") {
+		d = strings.TrimSuffix(d, "0s")
+	}
+	if strings.HasSuffix(d, "h0m") {
+		d = strings.TrimSuffix(d, "0m")
+	}
+	return composite("offset", d, schedule)
+}
+
+// Between returns a schedule expression keeping the activations of schedule within the daily window
+// from-to, like "08:00" and "18:00" (the end excluded), a window ending before its start crosses midnight
+func Between(from string, to string, schedule string) string {
+	return composite("between", from, to, schedule)
+}
+
+// composite returns the expression of a composite schedule
+func composite(name string, args ...string) string {
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		normalized[i] = normalizeSchedule(arg)
+	}
+	return "@" + name + "(" + strings.Join(normalized, "; ") + ")"
+}
+
+// intersectSchedule activates only when all of its schedules activate
+type intersectSchedule struct {
+	schedules []cron.Schedule
+}
+
+// unionSchedule activates when any of its schedules activates
+type unionSchedule struct {
+	schedules []cron.Schedule
+}
+
+// exceptSchedule activates when its schedule activates but the excluded one doesn't
+type exceptSchedule struct {
+	schedule cron.Schedule
+	excluded cron.Schedule
+}
+
+// offsetSchedule activates offset after each activation of its schedule
+type offsetSchedule struct {
+	offset   time.Duration
+	schedule cron.Schedule
+}
+
+// betweenSchedule keeps the activations of its schedule within a daily window,
+// from and to are the seconds since midnight of its start and its end
+type betweenSchedule struct {
+	from, to int
+	schedule cron.Schedule
+}
+
+// randomSchedule activates once at a random time within the window following
+// each activation of its schedule, the time is drawn again for every activation
+// from the seed, so that it can be predicted
+type randomSchedule struct {
+	window   time.Duration
+	schedule cron.Schedule
+	seed     uint64
+}
+
+// icalParameter matches the text following the ';' of an iCalendar parameter or rule part
+var icalParameter = regexp.MustCompile(`^[A-Z][A-Z-]*=`)
+
+// isComposite reports if a schedule expression is a composite schedule
+func isComposite(expr string) bool {
+	name, _, ok := splitComposite(expr)
+	if !ok {
+		return false
+	}
+	_, ok = compositeSchedules[name]
+	return ok
+}
+
+// splitComposite splits a composite schedule expression into its name and its arguments
+func splitComposite(expr string) (string, []string, bool) {
+	open := strings.Index(expr, "(")
+	if !strings.HasPrefix(expr, "@") || open < 0 || !strings.HasSuffix(expr, ")") {
+		return "", nil, false
+	}
+
+	var args []string
+	depth, start := 0, open+1
+	body := expr[:len(expr)-1]
+	for i := start; i < len(body); i++ {
+		switch body[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';':
+			// the ';' of the iCalendar parameters and rule parts, like ";BYDAY=", don't separate arguments
+			if depth == 0 && !icalParameter.MatchString(body[i+1:]) {
+				args = append(args, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	args = append(args, strings.TrimSpace(body[start:]))
+	return expr[1:open], args, true
+}
+
+// parseComposite parses a composite schedule expression like "@intersect(0 0 12 1-7 * *; 0 0 12 * * 1)"
+func parseComposite(expr string) (cron.Schedule, error) {
+	name, args, _ := splitComposite(expr)
+	schedule, err := compositeSchedules[name].parse(args)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Parsing @"+name+" schedule")
+	}
+	return schedule, nil
+}
+
+// parseScheduleArg parses an argument of a composite schedule that is a schedule expression
+func parseScheduleArg(arg string) (cron.Schedule, error) {
+	schedule, err := parseSchedule(arg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Parsing schedule '"+arg+"'")
+	}
+	return schedule, nil
+}
+
+func parseIntersect(args []string) (cron.Schedule, error) {
+	if len(args) < 2 {
+		return nil, errors.New("@intersect needs at least 2 schedules")
+	}
+	schedules, err := parseSchedules(args)
+	if err != nil {
+		return nil, err
+	}
+	return intersectSchedule{schedules: schedules}, nil
+}
+
+// parseSchedules parses the arguments of a composite schedule made only of schedules
+func parseSchedules(args []string) ([]cron.Schedule, error) {
+	var schedules []cron.Schedule
+	for _, arg := range args {
+		schedule, err := parseScheduleArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func parseUnion(args []string) (cron.Schedule, error) {
+	if len(args) < 2 {
+		return nil, errors.New("@union needs at least 2 schedules")
+	}
+	schedules, err := parseSchedules(args)
+	if err != nil {
+		return nil, err
+	}
+	return unionSchedule{schedules: schedules}, nil
+}
+
+func parseExcept(args []string) (cron.Schedule, error) {
+	if len(args) != 2 {
+		return nil, errors.New("@except needs a schedule and the schedule it excludes")
+	}
+	schedules, err := parseSchedules(args)
+	if err != nil {
+		return nil, err
+	}
+	return exceptSchedule{schedule: schedules[0], excluded: schedules[1]}, nil
+}
+
+func parseOffset(args []string) (cron.Schedule, error) {
+	if len(args) != 2 {
+		return nil, errors.New("@offset needs an offset and a schedule")
+	}
+	offset, err := time.ParseDuration(args[0])
+	if err != nil || offset%time.Second != 0 {
+		return nil, errors.New("the offset of @offset must be a duration in seconds, like 10m or -30s")
+	}
+	schedule, err := parseScheduleArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return offsetSchedule{offset: offset, schedule: schedule}, nil
+}
+
+func parseBetween(args []string) (cron.Schedule, error) {
+	if len(args) != 3 {
+		return nil, errors.New("@between needs a start, an end and a schedule")
+	}
+	var bounds []int
+	for _, arg := range args[:2] {
+		t, err := time.Parse("15:04", arg)
+		if err != nil {
+			if t, err = time.Parse("15:04:05", arg); err != nil {
+				return nil, errors.Errorf("the bounds of @between must be times of the day like 08:00, got '%s'", arg)
+			}
+		}
+		bounds = append(bounds, t.Hour()*3600+t.Minute()*60+t.Second())
+	}
+	if bounds[0] == bounds[1] {
+		return nil, errors.New("the window of @between is empty")
+	}
+	schedule, err := parseScheduleArg(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return betweenSchedule{from: bounds[0], to: bounds[1], schedule: schedule}, nil
+}
+
+func parseRandom(args []string) (cron.Schedule, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, errors.New("@random needs a window, a schedule and optionally a seed")
+	}
+	window, err := time.ParseDuration(args[0])
+	if err != nil || window < time.Second {
+		return nil, errors.New("the window of @random must be a duration of at least 1s, like 3h")
+	}
+	schedule, err := parseScheduleArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var seed uint64
+	if len(args) == 3 {
+		if seed, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+			return nil, errors.New("the seed of @random must be a positive number")
+		}
+	}
+	return randomSchedule{window: window, schedule: schedule, seed: seed}, nil
+}
+
+// Next returns the first activation after t shared by all the schedules
+func (s intersectSchedule) Next(t time.Time) time.Time {
+	next := s.schedules[0].Next(t)
+	for i := 0; i < maxCompositeSteps && !next.IsZero(); i++ {
+		agreed := true
+		for _, schedule := range s.schedules {
+			// the first activation of this schedule not before next
+			candidate := schedule.Next(next.Add(-time.Second))
+			if candidate.IsZero() {
+				return time.Time{}
+			}
+			if candidate.After(next) {
+				next = candidate
+				agreed = false
+			}
+		}
+		if agreed {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// Next returns the first activation after t of any of the schedules
+func (s unionSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range s.schedules {
+		if candidate := schedule.Next(t); !candidate.IsZero() && (next.IsZero() || candidate.Before(next)) {
+			next = candidate
+		}
+	}
+	return next
+}
+
+// Next returns the first activation after t not excluded
+func (s exceptSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxCompositeSteps; i++ {
+		next := s.schedule.Next(t)
+		if next.IsZero() || !s.excluded.Next(next.Add(-time.Second)).Equal(next) {
+			return next
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// Next returns the first shifted activation after t
+func (s offsetSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.Add(-s.offset))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(s.offset)
+}
+
+// Next returns the first activation after t within the window
+func (s betweenSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxCompositeSteps; i++ {
+		next := s.schedule.Next(t)
+		if next.IsZero() || s.within(next) {
+			return next
+		}
+		// skip to the start of the next window
+		y, m, d := next.Date()
+		start := time.Date(y, m, d, 0, 0, s.from, 0, next.Location())
+		if !start.After(next) {
+			start = time.Date(y, m, d+1, 0, 0, s.from, 0, next.Location())
+		}
+		t = start.Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+// within reports if t is within the window
+func (s betweenSchedule) within(t time.Time) bool {
+	seconds := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	if s.from < s.to {
+		return seconds >= s.from && seconds < s.to
+	}
+	return seconds >= s.from || seconds < s.to
+}
+
+// Next returns the first random activation after t
+func (s randomSchedule) Next(t time.Time) time.Time {
+	// the windows that may still hold an activation after t
+	start := s.schedule.Next(t.Add(-s.window))
+	for i := 0; i < maxCompositeSteps && !start.IsZero(); i++ {
+		if next := start.Add(s.offset(start)); next.After(t) {
+			return next
+		}
+		start = s.schedule.Next(start)
+	}
+	return time.Time{}
+}
+
+// offset returns the offset of the activation within the window starting at start
+func (s randomSchedule) offset(start time.Time) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(s.seed, 10) + "/" + strconv.FormatInt(start.Unix(), 10)))
+	return time.Duration(h.Sum64()%uint64(s.window/time.Second)) * time.Second
+}
@@ -0,0 +1,103 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestScheduleAlgebra(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{
+			// every 5 minutes, except during the backups from 02:00 to 03:00
+			Except("0 */5 * * * *", "* * 2 * * *"),
+			[]string{"2026-10-17T01:55:00Z", "2026-10-17T03:00:00Z", "2026-10-17T03:05:00Z"},
+		},
+		{
+			// hourly, but 10 minutes after the hour on weekends
+			Union("0 0 * * * 1-5", Offset(10*time.Minute, "0 0 * * * 0,6")),
+			[]string{"2026-10-17T02:10:00Z", "2026-10-17T03:10:00Z"},
+		},
+		{
+			Between("23:00", "01:00", "0 0 * * * *"),
+			[]string{"2026-10-17T23:00:00Z", "2026-10-18T00:00:00Z", "2026-10-18T23:00:00Z"},
+		},
+		{
+			Offset(-30*time.Second, "@daily"),
+			[]string{"2026-10-17T23:59:30Z", "2026-10-18T23:59:30Z"},
+		},
+		{
+			Intersect("0 0 12 * * 5", "0 0 12 13 * *"),
+			[]string{"2026-11-13T12:00:00Z", "2027-08-13T12:00:00Z"},
+		},
+	}
+
+	from := time.Date(2026, time.October, 17, 1, 50, 0, 0, time.UTC)
+	for _, test := range tests {
+		times, err := PreviewSchedule(test.expr, from, len(test.expected), "UTC")
+		if err != nil {
+			t.Errorf("PreviewSchedule('%s') returned %v", test.expr, err)
+			continue
+		}
+		for i, expected := range test.expected {
+			at, _ := time.Parse(time.RFC3339, expected)
+			if i >= len(times) || !times[i].Equal(at) {
+				t.Errorf("expected '%s' to activate at %v, got %v", test.expr, test.expected, times)
+				break
+			}
+		}
+	}
+
+	if expr := Offset(90*time.Minute, "@hourly"); expr != "@offset(1h30m; @hourly)" {
+		t.Errorf("expected Offset() to write '@offset(1h30m; @hourly)', got '%s'", expr)
+	}
+	for _, expr := range []string{"@union(@daily)", "@except(@daily; @hourly; @weekly)", "@offset(10ms; @daily)",
+		"@between(8am; 18:00; @hourly)", "@between(08:00; 08:00; @hourly)", "@offset(10m; 61 * * * * *)"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("expected parseSchedule('%s') to fail", expr)
+		}
+	}
+}
+
+func TestScheduleAlgebraJobs(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	_, err := Add(Union("0 0 9 * * *", "0 0 18 * * *"), Job{ID: "twice", Task: "echo 'twice'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times, err := NextRuns("twice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range times {
+		if at.Hour() != 9 && at.Hour() != 18 {
+			t.Errorf("expected the job to run at 09:00 and 18:00, got %v", times)
+		}
+	}
+
+	schedule := Except("0 */5 * * * *", "* * 2 * * *")
+	err = Update(schedule, Job{ID: "twice", Task: "echo 'twice'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := List()
+	if len(jobs[schedule]) != 1 {
+		t.Errorf("expected the composite schedule to be persisted with the job, got %v", jobs)
+	}
+	times, _ = NextRuns("twice", 50)
+	for _, at := range times {
+		if at.Hour() == 2 {
+			t.Errorf("expected the job not to run between 02:00 and 03:00, got %s", at)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package grontab
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the prefix of the fixed-delay schedules, "@delay 1m" runs 1m after the end of the previous run
+const delayPrefix = "@delay "
+
+// the timers of the fixed-delay jobs by job id (jid), armed by Start and stopped by Stop,
+// which waits for the runs in progress
+var (
+	delayTimers   = make(map[string]*time.Timer)
+	delayTimersMu sync.Mutex
+	delayStarted  bool
+	delayRuns     sync.WaitGroup
+)
+
+// the context of the fixed-delay runs, cancelled by Stop when they outlast Config.ShutdownTimeout
+var (
+	delayCtx    = context.Background()
+	delayCancel = context.CancelFunc(func() {})
+)
+
+// delaySchedule activates a delay after the end of the previous run,
+// its next activation is the one kept with the job, the following ones assume instant runs
+type delaySchedule struct {
+	delay time.Duration
+	next  time.Time
+}
+
+// isDelay reports if a schedule expression is a fixed-delay schedule
+func isDelay(expr string) bool {
+	return strings.HasPrefix(expr, delayPrefix)
+}
+
+// parseDelay parses a fixed-delay schedule expression like "@delay 1m"
+func parseDelay(expr string) (delaySchedule, error) {
+	delay, err := time.ParseDuration(strings.TrimPrefix(expr, delayPrefix))
+	if err != nil || delay < time.Second {
+		return delaySchedule{}, errors.New("the delay of @delay must be a duration of at least 1s, like 1m")
+	}
+	return delaySchedule{delay: delay}, nil
+}
+
+// Next returns the next activation of the job if it is after t, or t plus the delay
+func (s delaySchedule) Next(t time.Time) time.Time {
+	if s.next.After(t) {
+		return s.next
+	}
+	return t.Add(s.delay)
+}
+
+// nextDelayed returns the next activation of a fixed-delay job, a delay after t
+func nextDelayed(job Job, t time.Time) time.Time {
+	s, err := parseDelay(job.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.Add(s.delay)
+}
+
+// armDelayed sets the timer of a fixed-delay job at its next activation,
+// right away if it passed while grontab was down
+func armDelayed(job Job) {
+	delayTimersMu.Lock()
+	defer delayTimersMu.Unlock()
+	if timer, ok := delayTimers[job.ID]; ok {
+		timer.Stop()
+		delete(delayTimers, job.ID)
+	}
+	if !delayStarted || !job.Enabled || !isDelay(job.Schedule) {
+		return
+	}
+	jid := job.ID
+	delayTimers[jid] = time.AfterFunc(time.Until(job.DelayedNext), func() {
+		delayTimersMu.Lock()
+		if !delayStarted {
+			delayTimersMu.Unlock()
+			return
+		}
+		delayRuns.Add(1)
+		ctx := delayCtx
+		delayTimersMu.Unlock()
+
+		defer delayRuns.Done()
+		runDelayed(ctx, jid)
+	})
+}
+
+// disarmDelayed stops the timer of a fixed-delay job, if any
+func disarmDelayed(jid string) {
+	armDelayed(Job{ID: jid})
+}
+
+// runDelayed runs a fixed-delay job, killed when ctx is done, then arms it again a delay after the end of the run
+func runDelayed(ctx context.Context, jid string) {
+	var job Job
+	err := store.View(func(tx Tx) error {
+		var err error
+		job, err = tx.GetJob(jid)
+		return err
+	})
+	if err != nil || !job.Enabled || !isDelay(job.Schedule) {
+		// the job was removed, disabled or moved meanwhile
+		return
+	}
+
+	activation := time.Now()
+	var next time.Time
+	switch reason := skipReason(job, activation); {
+	case job.expiredAt(activation):
+		expireJob(jid)
+		return
+	case activation.Before(job.NotBefore):
+		next = job.NotBefore
+	case reason != "":
+		log.Printf(yellow("SKIP DLY[%s][%s]: %s"), job.Schedule, jid, reason)
+		recordRun(skippedRun(job, activation, reason))
+	default:
+		log.Printf(green("EXEC DLY[%s][%s]: %s"), job.Schedule, jid, job.Task)
+		run := runJobContext(ctx, job)
+		log.Printf(cyan("OUTP DLY[%s][%s]: %s"), job.Schedule, jid, strings.Replace(run.Output, "\n", "", -1))
+	}
+
+	// keep the next activation with the job, so that it survives the restarts
+	err = store.Update(func(tx Tx) error {
+		var err error
+		job, err = tx.GetJob(jid)
+		if err != nil {
+			return err
+		}
+		if !job.Enabled || !isDelay(job.Schedule) {
+			return nil
+		}
+		job.DelayedNext = next
+		if next.IsZero() {
+			job.DelayedNext = nextDelayed(job, time.Now())
+		}
+		return tx.PutJob(job)
+	})
+	if err != nil {
+		log.Printf(red("Error setting the next run of job %s: %s"), jid, err)
+		return
+	}
+	armDelayed(job)
+}
+
+// startDelayedJobs arms the timers of the enabled fixed-delay jobs
+func startDelayedJobs() {
+	var delayed []Job
+	err := store.View(func(tx Tx) error {
+		jobs, err := tx.EnabledJobs()
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if isDelay(job.Schedule) {
+				delayed = append(delayed, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(red("Error getting the fixed-delay jobs: %s"), err)
+		return
+	}
+
+	delayTimersMu.Lock()
+	delayStarted = true
+	delayCtx, delayCancel = context.WithCancel(context.Background())
+	delayTimersMu.Unlock()
+	for _, job := range delayed {
+		armDelayed(job)
+	}
+}
+
+// stopDelayedJobs stops the timers of the fixed-delay jobs and waits for the runs in progress,
+// killing the ones still running after Config.ShutdownTimeout
+func stopDelayedJobs() {
+	delayTimersMu.Lock()
+	for jid, timer := range delayTimers {
+		timer.Stop()
+		delete(delayTimers, jid)
+	}
+	delayStarted = false
+	cancel := delayCancel
+	delayTimersMu.Unlock()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		delayRuns.Wait()
+		close(done)
+	}()
+
+	timeout := shutdownTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf(red("The fixed-delay jobs still running after %s are killed"), timeout)
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,109 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFixedDelay(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+	defer stopDelayedJobs()
+
+	if _, err := Add("@delay 10ms", Job{Task: "echo 'fast'"}); err == nil {
+		t.Errorf("expected Add() to refuse a delay shorter than 1s")
+	}
+
+	added := time.Now()
+	id, err := Add("@delay 1s", Job{ID: "poll", Task: "sleep 1", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _ := getTestJob(id)
+	if job.DelayedNext.Before(added.Add(time.Second)) {
+		t.Errorf("expected the first run a delay after the addition, got %s", job.DelayedNext)
+	}
+	if times, _ := NextRuns(id, 2); len(times) != 2 || !times[0].Equal(job.DelayedNext) || times[1].Sub(times[0]) != time.Second {
+		t.Errorf("unexpected next runs %v", times)
+	}
+
+	// the gap between the runs doesn't depend on their duration
+	deadline := time.Now().Add(10 * time.Second)
+	var runs []Run
+	for {
+		if runs, _ = History(id); len(runs) >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the job to run twice, got %v", runs)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if gap := runs[1].Start.Sub(runs[0].End); gap < time.Second {
+		t.Errorf("expected the runs to be 1s apart, got %s", gap)
+	}
+
+	// the next run is kept with the job, and restored at Start
+	stopDelayedJobs()
+	job, _ = getTestJob(id)
+	if job.DelayedNext.Before(runs[1].End) {
+		t.Errorf("expected the next run to be kept after the last one, got %s", job.DelayedNext)
+	}
+	err = store.Update(func(tx Tx) error {
+		job.DelayedNext = time.Now().Add(-time.Hour)
+		return tx.PutJob(job)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := len(runs)
+	startDelayedJobs()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if runs, _ = History(id); len(runs) > before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the missed run to run at Start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if description, _ := Explain("@delay 1m"); description != "1m0s after the end of each run" {
+		t.Errorf("unexpected description '%s'", description)
+	}
+}
+
+func TestStopDelayedJobsTimeout(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true,
+		ShutdownTimeout: 200 * time.Millisecond})
+	Start()
+
+	id, err := Add("@delay 1s", Job{ID: "slow", Task: "sleep 10", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first run starts a delay after the addition
+	time.Sleep(1500 * time.Millisecond)
+
+	// the run still in progress after the ShutdownTimeout is killed
+	stopped := time.Now()
+	stopDelayedJobs()
+	if elapsed := time.Since(stopped); elapsed > 5*time.Second {
+		t.Errorf("expected the stop to wait about the ShutdownTimeout, waited %s", elapsed)
+	}
+	if runs, _ := History(id); len(runs) != 1 || runs[0].Status == RunSucceeded {
+		t.Errorf("expected the run to be killed, got %v", runs)
+	}
+}
@@ -0,0 +1,617 @@
+package grontab
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Explain returns an english description of a schedule expression
+func Explain(expr string) (string, error) {
+	return explain(expr)
+}
+
+// the names of the months and of the days of the week, by value
+var (
+	monthNames = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+// the short names accepted in the month and day of week fields
+var (
+	monthValues = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	weekdayValues = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// the descriptions of the predefined schedules
+var descriptorExplanations = map[string]string{
+	"@yearly":   "at 00:00:00 on January 1st",
+	"@annually": "at 00:00:00 on January 1st",
+	"@monthly":  "at 00:00:00 on the first day of every month",
+	"@weekly":   "at 00:00:00 every Sunday",
+	"@daily":    "at 00:00:00 every day",
+	"@midnight": "at 00:00:00 every day",
+	"@hourly":   "at minute 0 of every hour",
+}
+
+// field describes a field of a schedule expression in english
+type field struct {
+	expr   string
+	unit   string
+	names  []string
+	values map[string]int
+}
+
+func explain(expr string) (string, error) {
+	// validate the expression with the same parser used by the engine
+	if _, err := parseSchedule(expr); err != nil {
+		return "", errors.Wrap(err, "Error Parsing schedule")
+	}
+
+	expr = normalizeSchedule(expr)
+	if isComposite(expr) {
+		return explainComposite(expr)
+	}
+	if isRuleSet(expr) {
+		s, _ := parseRuleSet(expr)
+		return explainRuleSet(s), nil
+	}
+	if isLifecycle(expr) {
+		s, _ := parseLifecycle(expr)
+		switch {
+		case s.event == shutdownSchedule:
+			return "each time grontab stops", nil
+		case s.delay > 0:
+			return s.delay.String() + " after each start of grontab", nil
+		}
+		return "each time grontab starts", nil
+	}
+	if isDelay(expr) {
+		s, _ := parseDelay(expr)
+		return s.delay.String() + " after the end of each run", nil
+	}
+	if isAt(expr) {
+		return "once at " + strings.TrimPrefix(expr, atPrefix), nil
+	}
+	if strings.HasPrefix(expr, "@every ") {
+		return "every " + strings.TrimPrefix(expr, "@every "), nil
+	}
+	if description, ok := descriptorExplanations[expr]; ok {
+		return description, nil
+	}
+
+	fields := expandScheduleFields(strings.Fields(expr))
+	second := field{expr: fields[0], unit: "second"}
+	minute := field{expr: fields[1], unit: "minute"}
+	hour := field{expr: fields[2], unit: "hour"}
+	dom := field{expr: fields[3], unit: "day"}
+	month := field{expr: fields[4], unit: "month", names: monthNames, values: monthValues}
+	dow := field{expr: fields[5], unit: "day of the week", names: weekdayNames, values: weekdayValues}
+
+	parts := []string{explainTime(second, minute, hour)}
+	fixedTime := second.fixed() && minute.fixed() && hour.fixed()
+
+	switch {
+	case dom.any() && dow.any():
+		if fixedTime {
+			parts = append(parts, "every day")
+		}
+	case dow.any():
+		parts = append(parts, "on "+dom.describeDays())
+	case dom.any():
+		parts = append(parts, "on "+dow.describe())
+	default:
+		// when both are restricted, either of them activates the schedule
+		parts = append(parts, "on "+dom.describeDays()+" or on "+dow.describe())
+	}
+
+	if !month.any() {
+		parts = append(parts, "in "+month.describe())
+	}
+	if len(fields) == 7 {
+		year := field{expr: fields[6], unit: "year"}
+		if !year.any() {
+			parts = append(parts, "in "+year.describe())
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// explainTime describes the second, minute and hour fields of a schedule
+func explainTime(second, minute, hour field) string {
+	if second.hashed() || minute.hashed() || hour.hashed() {
+		return explainHashedTime(second, minute, hour)
+	}
+	if second.single() && minute.single() && hour.single() {
+		return fmt.Sprintf("at %02d:%02d:%02d", hour.value(), minute.value(), second.value())
+	}
+
+	window, step, windowed := hourWindow(hour)
+	hourly := false
+
+	var phrase string
+	switch {
+	case !second.single():
+		phrase = second.describe()
+		if !minute.any() {
+			phrase += ", " + minute.describe()
+		}
+	case !minute.single() && second.value() == 0:
+		phrase = minute.describe()
+	case !minute.single():
+		phrase = fmt.Sprintf("at second %d of %s", second.value(), minute.describe())
+	case windowed && second.value() == 0 && minute.value() == 0:
+		// the hours are the frequency
+		hourly = true
+		phrase = "every hour"
+		if step != "" {
+			phrase = "every " + step + " hours"
+		}
+	default:
+		return fmt.Sprintf("at minute %d, second %d of %s", minute.value(), second.value(), hour.describe())
+	}
+
+	switch {
+	case windowed && (hourly || step == ""):
+		if window != "" {
+			phrase += " " + window
+		}
+	case !hour.any():
+		phrase += ", " + hour.describe()
+	}
+	return phrase
+}
+
+// explainHashedTime describes a time with fields derived from the job ID
+func explainHashedTime(second, minute, hour field) string {
+	var fixed, others []string
+	for _, f := range []field{second, minute, hour} {
+		switch {
+		case f.fixed():
+			fixed = append(fixed, f.describe())
+		case !f.any():
+			others = append(others, f.describe())
+		}
+	}
+
+	if len(others) > 0 {
+		return strings.Join(others, ", ") + ", at " + strings.Join(fixed, ", ")
+	}
+	phrase := "at " + strings.Join(fixed, ", ")
+	switch {
+	case minute.any():
+		phrase += " of every minute"
+	case hour.any():
+		phrase += " of every hour"
+	}
+	return phrase
+}
+
+// hourWindow splits an hour field made of a single range, like "8-17/2",
+// into its window ("between 08:00 and 18:00", empty for "*") and its step
+func hourWindow(hour field) (string, string, bool) {
+	if strings.Contains(hour.expr, ",") || hour.hashed() {
+		return "", "", false
+	}
+
+	rangeAndStep := strings.SplitN(hour.expr, "/", 2)
+	step := ""
+	if len(rangeAndStep) == 2 {
+		step = rangeAndStep[1]
+	}
+	if rangeAndStep[0] == "*" || rangeAndStep[0] == "?" {
+		return "", step, true
+	}
+
+	lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+	if len(lowAndHigh) != 2 {
+		return "", "", false
+	}
+	end := "midnight"
+	if high := hour.parse(lowAndHigh[1]); high < 23 {
+		end = fmt.Sprintf("%02d:00", high+1)
+	}
+	return fmt.Sprintf("between %02d:00 and %s", hour.parse(lowAndHigh[0]), end), step, true
+}
+
+// explainComposite describes a composite schedule
+func explainComposite(expr string) (string, error) {
+	name, args, _ := splitComposite(expr)
+	switch name {
+	case "intersect":
+		if description, ok := explainOrdinalWeekday(args); ok {
+			return description, nil
+		}
+	case "random":
+		description, err := explain(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "once at a random time within " + args[0] + " of each activation " + description, nil
+	case "except":
+		description, err := explain(args[0])
+		if err != nil {
+			return "", err
+		}
+		excluded, err := explain(args[1])
+		if err != nil {
+			return "", err
+		}
+		return description + ", except " + excluded, nil
+	case "offset":
+		description, err := explain(args[1])
+		if err != nil {
+			return "", err
+		}
+		offset, _ := time.ParseDuration(args[0])
+		if offset < 0 {
+			return strings.TrimPrefix(args[0], "-") + " before each activation " + description, nil
+		}
+		return args[0] + " after each activation " + description, nil
+	case "between":
+		description, err := explain(args[2])
+		if err != nil {
+			return "", err
+		}
+		return description + ", only between " + args[0] + " and " + args[1], nil
+	}
+
+	var descriptions []string
+	for _, arg := range args {
+		description, err := explain(arg)
+		if err != nil {
+			return "", err
+		}
+		descriptions = append(descriptions, "("+description+")")
+	}
+	return name + " of " + strings.Join(descriptions, ", "), nil
+}
+
+// explainOrdinalWeekday describes the intersection of a week of the month and a weekday,
+// e.g. "at 12:00:00 on the first Monday of the month", as generated by ParseNatural
+func explainOrdinalWeekday(args []string) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+	week, weekday := strings.Fields(args[0]), strings.Fields(args[1])
+	if len(week) != 6 || len(weekday) != 6 || week[5] != "*" || weekday[3] != "*" ||
+		strings.Join(week[:3], " ") != strings.Join(weekday[:3], " ") || week[4] != weekday[4] {
+		return "", false
+	}
+
+	nth := 0
+	for i := 1; i < len(ordinalNames); i++ {
+		if week[3] == fmt.Sprintf("%d-%d", 7*i-6, minInt(7*i, 31)) {
+			nth = i
+		}
+	}
+	dow := field{expr: weekday[5], unit: "day of the week", names: weekdayNames, values: weekdayValues}
+	if nth == 0 || !dow.single() {
+		return "", false
+	}
+
+	parts := []string{explainTime(field{expr: week[0], unit: "second"}, field{expr: week[1], unit: "minute"}, field{expr: week[2], unit: "hour"}),
+		"on the " + ordinalNames[nth] + " " + dow.describe() + " of the month"}
+	month := field{expr: week[4], unit: "month", names: monthNames, values: monthValues}
+	if !month.any() {
+		parts = append(parts, "in "+month.describe())
+	}
+	return strings.Join(parts, " "), true
+}
+
+// expandScheduleFields fills in the optional day of week field of a schedule
+func expandScheduleFields(fields []string) []string {
+	if len(fields) == 5 {
+		fields = append(fields, "*")
+	}
+	return fields
+}
+
+// any reports if the field matches every value
+func (f field) any() bool {
+	return f.expr == "*" || f.expr == "?"
+}
+
+// single reports if the field matches exactly one value
+func (f field) single() bool {
+	return !f.hashed() && !strings.ContainsAny(f.expr, "*?,-/")
+}
+
+// hashed reports if the field is derived from the job ID
+func (f field) hashed() bool {
+	return strings.HasPrefix(f.expr, "H")
+}
+
+// fixed reports if the field matches exactly one value, possibly derived from the job ID
+func (f field) fixed() bool {
+	if f.hashed() {
+		return !strings.ContainsAny(f.expr, ",/")
+	}
+	return f.single()
+}
+
+// value returns the value of a single valued field
+func (f field) value() int {
+	return f.parse(f.expr)
+}
+
+// parse returns the numeric value of a token, resolving names
+func (f field) parse(token string) int {
+	if v, ok := f.values[strings.ToLower(token)]; ok {
+		return v
+	}
+	v, _ := strconv.Atoi(token)
+	return v
+}
+
+// name returns the english name of a token
+func (f field) name(token string) string {
+	v := f.parse(token)
+	if f.names != nil && v >= 0 && v < len(f.names) {
+		return f.names[v]
+	}
+	return strconv.Itoa(v)
+}
+
+// describe describes the field, e.g. "every 10 seconds" or "Monday through Friday"
+func (f field) describe() string {
+	if f.any() {
+		return "every " + f.unit
+	}
+
+	var phrases []string
+	for _, r := range strings.Split(f.expr, ",") {
+		phrases = append(phrases, f.describeRange(r))
+	}
+	return joinPhrases(phrases)
+}
+
+// joinPhrases joins phrases as an english list, e.g. "a, b and c"
+func joinPhrases(phrases []string) string {
+	if len(phrases) == 1 {
+		return phrases[0]
+	}
+	return strings.Join(phrases[:len(phrases)-1], ", ") + " and " + phrases[len(phrases)-1]
+}
+
+// describeRange describes a range of the field like "a-b/n"
+func (f field) describeRange(r string) string {
+	if strings.HasPrefix(r, "H") {
+		return f.describeHash(r)
+	}
+	if description, ok := f.describeQuartz(r); ok {
+		return description
+	}
+
+	rangeAndStep := strings.SplitN(r, "/", 2)
+	lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+
+	var span string
+	switch {
+	case lowAndHigh[0] == "*" || lowAndHigh[0] == "?":
+		span = ""
+	case len(lowAndHigh) == 2:
+		span = fmt.Sprintf("%s through %s", f.name(lowAndHigh[0]), f.name(lowAndHigh[1]))
+	case f.names != nil:
+		span = f.name(lowAndHigh[0])
+	default:
+		span = fmt.Sprintf("%s %s", f.unit, f.name(lowAndHigh[0]))
+	}
+
+	if len(rangeAndStep) == 1 {
+		if f.names == nil && len(lowAndHigh) == 2 {
+			return f.unit + "s " + span
+		}
+		return span
+	}
+
+	step := "every " + rangeAndStep[1] + " " + f.unit + "s"
+	switch {
+	case span == "":
+		return step
+	case len(lowAndHigh) == 1:
+		return step + " starting at " + f.name(lowAndHigh[0])
+	}
+	return step + " from " + span
+}
+
+// describeHash describes a range derived from the job ID like "H(a-b)/n"
+func (f field) describeHash(r string) string {
+	rangeAndStep := strings.SplitN(r[1:], "/", 2)
+	description := "the " + f.unit + " derived from the job ID"
+	if len(rangeAndStep) == 2 {
+		description = "every " + rangeAndStep[1] + " " + f.unit + "s starting at " + description
+	}
+	if within := strings.Trim(rangeAndStep[0], "()"); within != "" {
+		lowAndHigh := strings.SplitN(within, "-", 2)
+		if len(lowAndHigh) == 2 {
+			description += " between " + f.name(lowAndHigh[0]) + " and " + f.name(lowAndHigh[1])
+		}
+	}
+	return description
+}
+
+// describeQuartz describes the quartz-style day values: "L", "L-n", "LW" and "nW"
+// of the day of month field, "nL" and "n#k" of the day of week field
+func (f field) describeQuartz(r string) (string, bool) {
+	switch {
+	case f.unit == "day" && r == "L":
+		return "the last day", true
+	case f.unit == "day" && strings.HasPrefix(r, "L-"):
+		return r[2:] + " days before the last day", true
+	case f.unit == "day" && r == "LW":
+		return "the last weekday", true
+	case f.unit == "day" && strings.HasSuffix(r, "W"):
+		return "the weekday nearest to day " + strings.TrimSuffix(r, "W"), true
+	case f.names == nil || f.unit == "month":
+		return "", false
+	case strings.Contains(r, "#"):
+		weekdayAndNth := strings.SplitN(r, "#", 2)
+		nth, _ := strconv.Atoi(weekdayAndNth[1])
+		if nth < 1 || nth >= len(ordinalNames) {
+			return "", false
+		}
+		return "the " + ordinalNames[nth] + " " + f.name(weekdayAndNth[0]) + " of the month", true
+	case len(r) > 1 && strings.HasSuffix(r, "L"):
+		return "the last " + f.name(strings.TrimSuffix(r, "L")) + " of the month", true
+	}
+	return "", false
+}
+
+// describeDays describes a day of month field
+func (f field) describeDays() string {
+	return f.describe() + " of the month"
+}
+
+// explainRuleSet describes an iCalendar recurrence set
+func explainRuleSet(s *ruleSet) string {
+	var rules []string
+	for _, rule := range s.rrules {
+		rules = append(rules, explainRule(rule))
+	}
+	parts := []string{strings.Join(rules, " and ")}
+
+	if len(s.rdates) > 0 {
+		var dates []string
+		for _, rdate := range s.rdates {
+			dates = append(dates, rdate.Format("2006-01-02 15:04:05"))
+		}
+		parts = append(parts, "and on "+joinPhrases(dates))
+	}
+	parts = append(parts, "starting "+s.dtstart.Format("2006-01-02 15:04:05")+" "+s.dtstart.Location().String())
+
+	var excluded []string
+	for _, rule := range s.exrules {
+		excluded = append(excluded, explainRule(rule))
+	}
+	var dates []string
+	for _, exdate := range s.exdates {
+		dates = append(dates, exdate.Format("2006-01-02 15:04:05"))
+	}
+	for day := range s.exdays {
+		t, _ := time.Parse(icalDate, day)
+		dates = append(dates, t.Format("2006-01-02"))
+	}
+	sort.Strings(dates)
+	if len(dates) > 0 {
+		excluded = append(excluded, "on "+joinPhrases(dates))
+	}
+	if len(excluded) > 0 {
+		parts = append(parts, "except "+strings.Join(excluded, " and except "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// explainRule describes an RRULE or an EXRULE, e.g. "every 2 weeks on Tuesday and Thursday at 09:00:00"
+func explainRule(r *recurrenceRule) string {
+	parts := []string{"every " + ruleUnits[r.freq]}
+	if r.interval > 1 {
+		parts[0] = fmt.Sprintf("every %d %ss", r.interval, ruleUnits[r.freq])
+	}
+
+	if len(r.byMonth) > 0 {
+		var months []string
+		for _, month := range r.byMonth {
+			months = append(months, monthNames[month])
+		}
+		parts = append(parts, "in "+joinPhrases(months))
+	}
+	if len(r.byMonthDay) > 0 {
+		var days []string
+		for _, day := range r.byMonthDay {
+			days = append(days, describeRuleDay(day, "month"))
+		}
+		parts = append(parts, "on "+joinPhrases(days))
+	}
+	if len(r.byYearDay) > 0 {
+		var days []string
+		for _, day := range r.byYearDay {
+			days = append(days, describeRuleDay(day, "year"))
+		}
+		parts = append(parts, "on "+joinPhrases(days))
+	}
+	if len(r.byDay) > 0 {
+		scope := "month"
+		if r.freq == yearly && len(r.byMonth) == 0 {
+			scope = "year"
+		}
+		var weekdays []string
+		for _, wd := range r.byDay {
+			if wd.n == 0 {
+				weekdays = append(weekdays, weekdayNames[wd.weekday])
+				continue
+			}
+			weekdays = append(weekdays, "the "+describeRuleOrdinal(wd.n)+" "+weekdayNames[wd.weekday]+" of the "+scope)
+		}
+		parts = append(parts, "on "+joinPhrases(weekdays))
+	}
+
+	if r.freq >= daily {
+		hours, minutes, seconds := r.timesOf(r.dtstart)
+		var times []string
+		for _, h := range hours {
+			for _, m := range minutes {
+				for _, s := range seconds {
+					times = append(times, fmt.Sprintf("%02d:%02d:%02d", h, m, s))
+				}
+			}
+		}
+		parts = append(parts, "at "+joinPhrases(times))
+	} else {
+		for _, by := range []struct {
+			unit   string
+			values []int
+		}{{"hour", r.byHour}, {"minute", r.byMinute}, {"second", r.bySecond}} {
+			if len(by.values) == 0 {
+				continue
+			}
+			var values []string
+			for _, v := range by.values {
+				values = append(values, strconv.Itoa(v))
+			}
+			parts = append(parts, "at "+by.unit+" "+joinPhrases(values))
+		}
+	}
+
+	if len(r.bySetPos) > 0 {
+		var positions []string
+		for _, position := range r.bySetPos {
+			positions = append(positions, describeRuleOrdinal(position))
+		}
+		parts = append(parts, "only the "+joinPhrases(positions)+" of each "+ruleUnits[r.freq])
+	}
+	if r.count > 0 {
+		parts = append(parts, fmt.Sprintf("for %d occurrences", r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "until "+r.until.Format("2006-01-02 15:04:05")+" "+r.until.Location().String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeRuleDay describes a day of the month or the year, counted from its end if negative
+func describeRuleDay(day int, scope string) string {
+	if day > 0 {
+		return fmt.Sprintf("day %d of the %s", day, scope)
+	}
+	return "the " + describeRuleOrdinal(day) + " day of the " + scope
+}
+
+// describeRuleOrdinal describes an ordinal, counted from the end if negative: "first", "last", "2nd to last"
+func describeRuleOrdinal(n int) string {
+	switch {
+	case n == -1:
+		return "last"
+	case n < 0:
+		return describeRuleOrdinal(-n) + " to last"
+	case n < len(ordinalNames):
+		return ordinalNames[n]
+	}
+	return fmt.Sprintf("%dth", n)
+}
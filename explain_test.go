@@ -0,0 +1,32 @@
+package grontab
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	cases := map[string]string{
+		"0 0 6 * * *":    "at 06:00:00 every day",
+		"*/10 * * * * *": "every 10 seconds",
+		"0 */5 * * * *":  "every 5 minutes",
+		"0 30 6 * * 1-5": "at 06:30:00 on Monday through Friday",
+		"0 0 0 13 * 5":   "at 00:00:00 on day 13 of the month or on Friday",
+		"@daily":         "at 00:00:00 every day",
+		"@every 1h30m":   "every 1h30m",
+	}
+	for expr, expected := range cases {
+		description, err := Explain(expr)
+		if err != nil {
+			t.Errorf("expected Explain('%s') to succeed, got %v", expr, err)
+			continue
+		}
+		if description != expected {
+			t.Errorf("expected Explain('%s') to be '%s', got '%s'", expr, expected, description)
+		}
+	}
+
+	_, err := Explain("not a schedule")
+	if err == nil {
+		t.Errorf("expected Explain() to refuse an invalid schedule")
+	}
+}
@@ -0,0 +1,170 @@
+package grontab
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Filter defines which jobs are returned by List and ListJobs,
+// the zero value matches every job
+type Filter struct {
+	// Labels is a label selector, a comma separated list of requirements:
+	// "key=value", "key==value", "key!=value", "key" (exists) and "!key" (doesn't exist)
+	Labels string
+	// Enabled, if set, matches only the jobs with the same enabled state
+	Enabled *bool
+	// Schedule, if set, matches only the jobs at the same schedule, or having it among their schedules
+	Schedule string
+	// Name, if set, matches only the jobs whose name contains it (case insensitive)
+	Name string
+}
+
+// labelRequirement is a single requirement of a label selector
+type labelRequirement struct {
+	key      string
+	value    string
+	operator string
+}
+
+// ListJobs returns the jobs matching the filter, sorted by schedule, name and id
+func ListJobs(filter Filter) ([]Job, error) {
+	return listJobs(filter)
+}
+
+func listJobs(filter Filter) ([]Job, error) {
+	requirements, err := parseLabelSelector(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []Job
+	err = store.View(func(tx Tx) error {
+		// the enabled jobs are looked up by their index
+		jobs, err := tx.Jobs()
+		if filter.Enabled != nil && *filter.Enabled {
+			jobs, err = tx.EnabledJobs()
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if c != nil {
+			now = now.In(c.Location())
+		}
+		for _, job := range jobs {
+			if !filter.matches(job, requirements) {
+				continue
+			}
+
+			// fill in the next activation from the schedule
+			// and the last one from the history
+			if schedule, err := jobRunSchedule(tx, job); err == nil {
+				job.Next = schedule.Next(now)
+			}
+			runs, err := tx.Runs(job.ID)
+			if err != nil {
+				return err
+			}
+			if len(runs) > 0 {
+				job.Prev = runs[len(runs)-1].Start
+			}
+
+			matching = append(matching, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Listing jobs")
+	}
+
+	sortJobs(matching)
+	return matching, nil
+}
+
+// matches reports if a job satisfies the filter and its parsed label requirements
+func (f Filter) matches(job Job, requirements []labelRequirement) bool {
+	if f.Enabled != nil && job.Enabled != *f.Enabled {
+		return false
+	}
+	if f.Schedule != "" && job.Schedule != normalizeSchedule(f.Schedule) && !containsString(jobSchedules(job), normalizeSchedule(f.Schedule)) {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(job.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	for _, r := range requirements {
+		value, ok := job.Labels[r.key]
+		switch r.operator {
+		case "=":
+			if !ok || value != r.value {
+				return false
+			}
+		case "!=":
+			if ok && value == r.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseLabelSelector parses a label selector like "team=billing,env!=dev"
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var r labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = labelRequirement{key: kv[0], value: kv[1], operator: "!="}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			r = labelRequirement{key: kv[0], value: kv[1], operator: "="}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = labelRequirement{key: kv[0], value: kv[1], operator: "="}
+		case strings.HasPrefix(part, "!"):
+			r = labelRequirement{key: part[1:], operator: "!exists"}
+		default:
+			r = labelRequirement{key: part, operator: "exists"}
+		}
+
+		r.key = strings.TrimSpace(r.key)
+		r.value = strings.TrimSpace(r.value)
+		if r.key == "" || strings.ContainsAny(r.key, "!=") || strings.ContainsAny(r.value, "!=") {
+			return nil, errors.New("Invalid label selector requirement: '" + part + "'")
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// sortJobs sorts jobs by schedule, name and id
+func sortJobs(jobs []Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Schedule != jobs[j].Schedule {
+			return jobs[i].Schedule < jobs[j].Schedule
+		}
+		if jobs[i].Name != jobs[j].Name {
+			return jobs[i].Name < jobs[j].Name
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
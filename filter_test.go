@@ -0,0 +1,64 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+)
+
+func TestListJobsFilter(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	Add("*/10 * * * * *", Job{Task: "echo 'a'", Name: "invoices", Enabled: true, Labels: map[string]string{"team": "billing", "env": "prod"}})
+	Add("*/10 * * * * *", Job{Task: "echo 'b'", Name: "reminders", Enabled: true, Labels: map[string]string{"team": "billing", "env": "dev"}})
+	Add("*/20 * * * * *", Job{Task: "echo 'c'", Name: "backup", Enabled: false, Labels: map[string]string{"team": "ops"}})
+
+	enabled := true
+	cases := []struct {
+		filter   Filter
+		expected []string
+	}{
+		{Filter{}, []string{"invoices", "reminders", "backup"}},
+		{Filter{Labels: "team=billing,env!=dev"}, []string{"invoices"}},
+		{Filter{Labels: "!env"}, []string{"backup"}},
+		{Filter{Enabled: &enabled}, []string{"invoices", "reminders"}},
+		{Filter{Schedule: "*/20  * * * * *"}, []string{"backup"}},
+		{Filter{Name: "INV"}, []string{"invoices"}},
+	}
+
+	for _, tc := range cases {
+		jobs, err := ListJobs(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+		if len(names) != len(tc.expected) {
+			t.Errorf("expected ListJobs(%+v) to return %v, got %v", tc.filter, tc.expected, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tc.expected[i] {
+				t.Errorf("expected ListJobs(%+v) to return %v, got %v", tc.filter, tc.expected, names)
+				break
+			}
+		}
+	}
+
+	_, err := ListJobs(Filter{Labels: "team=a=b"})
+	if err == nil {
+		t.Errorf("expected ListJobs() to refuse an invalid label selector")
+	}
+
+	grontabMap := List(Filter{Labels: "team=billing"})
+	if len(grontabMap) != 1 || len(grontabMap["*/10 * * * * *"]) != 2 {
+		t.Errorf("expected List() to group the filtered jobs by schedule, got %v", grontabMap)
+	}
+}
@@ -1,19 +1,23 @@
 package grontab
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/asdine/storm"
 	"github.com/damdo/randid"
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/wgliang/cron"
-	bolt "go.etcd.io/bbolt"
 )
 
 // Config defines a package configuration
@@ -23,16 +27,88 @@ type Config struct {
 	DisableParallelism bool
 	HideBanner         bool
 	TurnOffLogs        bool
+	HistorySize        int
+	Store              Store
+	// ShutdownTimeout bounds the time given by Stop to the @shutdown jobs and to the fixed-delay runs in progress, 30s if not set
+	ShutdownTimeout time.Duration
+	// MaxConcurrentJobs, if set, bounds the jobs running at once in the whole engine,
+	// the waiting jobs start by Priority
+	MaxConcurrentJobs int
+	// MaxGroupParallelism, if set, bounds the jobs of a schedule running at once,
+	// DisableParallelism is the same as 1
+	MaxGroupParallelism int
+	// LockDir, if set, backs the locks of the jobs with flock on the <key>.lock files in it,
+	// so that the other processes of the host can take the same locks
+	LockDir string
+	// ReadOnly opens the storage for inspecting it, like the grontab command does: the bolt file is opened read only,
+	// the storage isn't migrated, the schedules aren't registered and Check reports only the storage issues
+	ReadOnly bool
 }
 
-// Job defines a job
+// Job defines a job, its Schedule is set by Add and Update,
+// Next and Prev are the next and the last activation times, filled in by the listings
 type Job struct {
-	ID      string
-	Task    string
-	Enabled bool
+	ID       string `storm:"id"`
+	Schedule string `storm:"index"`
+	// Schedules are the schedules of a job set by AddSchedules and UpdateSchedules,
+	// its Schedule is then their union
+	Schedules   []string
+	Task        string
+	Enabled     bool `storm:"index"`
+	Tags        []string
+	Name        string
+	Description string
+	Owner       string
+	Labels      map[string]string
+	// IncludeCalendars restricts the job to the days of these calendars,
+	// ExcludeCalendars skips the days of these calendars
+	IncludeCalendars []string
+	ExcludeCalendars []string
+	// NotBefore and NotAfter, if set, bound the activations of the job,
+	// past NotAfter or after MaxRuns runs the job expires: it is disabled, or removed if RemoveOnExpire
+	NotBefore      time.Time
+	NotAfter       time.Time
+	MaxRuns        int
+	RemoveOnExpire bool
+	// SkipMissed expires a RunAt job whose time passed while grontab was down, instead of running it at Start
+	SkipMissed bool
+	// RunCount is the number of runs of the job, kept by grontab
+	RunCount int
+	// DelayedNext is the next activation of a fixed-delay ("@delay 1m") job,
+	// a delay after the end of its previous run, kept by grontab
+	DelayedNext time.Time
+	// Result, if set, is where the result of a run is taken from: ResultStdout or ResultFile
+	Result string
+	// Inputs are the jobs whose last results are given to the task in the GRONTAB_RESULT_<ID> environment variables
+	Inputs []string
+	// Timeout, if set, kills the task running longer
+	Timeout time.Duration
+	// OnSuccess, OnFailure and OnTimeout are the actions run after the task succeeded, failed or timed out,
+	// a timed out task without OnTimeout actions runs the OnFailure ones
+	OnSuccess []Action
+	OnFailure []Action
+	OnTimeout []Action
+	// Precondition is checked before each run, which is skipped if it doesn't hold
+	Precondition Precondition
+	// Guard skips or defers the runs while the system is under pressure
+	Guard Guard
+	// Priority decides which jobs start first, the highest first, when the jobs running at once are bounded
+	Priority int
+	// Order is the position of the job among the jobs at the same schedule, the lowest starts first,
+	// they run one after the other with DisableParallelism
+	Order int
+	// StopOnFailure skips the jobs at the same schedule not started yet when the job fails
+	StopOnFailure bool
+	// Locks are the keys of the locks held by the runs of the job, which never overlap with
+	// the runs of the jobs sharing a key: a run waits up to LockTimeout for them, then it is skipped
+	Locks       []string
+	LockTimeout time.Duration
+	Next        time.Time `json:"-"`
+	Prev        time.Time `json:"-"`
 }
 
-// jobDetails define details for a job
+// jobDetails define details for a job in the legacy storage layout,
+// where the jobs were grouped in maps keyed by schedule
 type jobDetails struct {
 	Task    string
 	Enabled bool
@@ -51,10 +127,21 @@ var grontabConfiguration Config
 
 // the cron instance
 var c *cron.Cron
-var db = new(storm.DB)
 
-// a map that keeps track of the gid and its corresponding ugid
-var ugidTable = make(map[string]string)
+// started reports if the engine was started, Stop runs the @shutdown jobs only then
+var started bool
+
+// the persistent storage
+var store Store
+
+// a map that keeps track of the gid and its corresponding ugid, and one of the schedules stored
+// by the jobs of each gid, which differ from it when they have H tokens,
+// guarded by ugidMu as the expiring jobs unregister their schedule from the cron workers
+var (
+	ugidTable    = make(map[string]string)
+	gidSchedules = make(map[string][]string)
+	ugidMu       sync.Mutex
+)
 
 // the banner string with the logo of the lib, to be printed in the cli
 var banner string = `
@@ -78,7 +165,18 @@ func Start() {
 
 // Add adds Job to a Schedule String
 func Add(schedule string, job Job) (string, error) {
-	return add(job.ID, schedule, jobDetails{Task: job.Task, Enabled: job.Enabled})
+	job.Schedule = normalizeSchedule(schedule)
+	job.Schedules = nil
+	return add(job)
+}
+
+// AddSchedules adds a Job running at each of the schedules, with a single ID, history and enabled flag
+func AddSchedules(schedules []string, job Job) (string, error) {
+	err := setSchedules(&job, schedules)
+	if err != nil {
+		return "", errors.Wrap(err, "Error Adding schedule to grontab")
+	}
+	return add(job)
 }
 
 // Remove removes a job
@@ -88,12 +186,29 @@ func Remove(id string) error {
 
 // Update updates a running job
 func Update(schedule string, job Job) error {
-	return update(job.ID, schedule, jobDetails{Task: job.Task, Enabled: job.Enabled})
+	job.Schedule = normalizeSchedule(schedule)
+	job.Schedules = nil
+	return update(job)
+}
+
+// UpdateSchedules updates a running job to run at each of the schedules
+func UpdateSchedules(schedules []string, job Job) error {
+	err := setSchedules(&job, schedules)
+	if err != nil {
+		return errors.Wrap(err, "Error Updating Job "+job.ID)
+	}
+	return update(job)
 }
 
-// List returns a list of the running schedules with their jobs
-func List() map[string][]Job {
-	return list()
+// List returns a list of the running schedules with their jobs,
+// optionally only the jobs matching the filter
+func List(filters ...Filter) map[string][]Job {
+	return list(filters)
+}
+
+// History returns the recorded runs of a job, oldest first
+func History(id string) ([]Run, error) {
+	return history(id)
 }
 
 // Stop stops the grontab engine
@@ -121,53 +236,73 @@ func initialize(config Config) error {
 		log.SetOutput(ioutil.Discard)
 	}
 
+	// open the storage, the bolt store is the default one
+	store = grontabConfiguration.Store
+	if store == nil {
+		var err error
+		store, err = openBoltStore(grontabConfiguration.PersistencePath, grontabConfiguration.BucketName, grontabConfiguration.ReadOnly)
+		if err != nil {
+			return errors.Wrap(err, "Error Initializing grontab")
+		}
+	}
+
+	// bring the storage up to the current schema version, a read only storage must already be
 	var err error
-	// open the db connection
-	db, err = storm.Open(grontabConfiguration.PersistencePath)
+	if grontabConfiguration.ReadOnly {
+		err = checkSchema()
+	} else {
+		err = migrateSchema()
+	}
 	if err != nil {
+		store.Close()
 		return errors.Wrap(err, "Error Initializing grontab")
 	}
 
+	// bound the jobs running at once
+	pool = newWorkerPool(grontabConfiguration.MaxConcurrentJobs)
+
 	// create a new cron instance
 	c = cron.New()
+	started = false
+	ugidMu.Lock()
+	ugidTable = make(map[string]string)
+	gidSchedules = make(map[string][]string)
+	ugidMu.Unlock()
+
+	// a read only storage is inspected, its jobs aren't run
+	if grontabConfiguration.ReadOnly {
+		return nil
+	}
 
-	// get keys from the storage
-	keys, err2 := getKeys()
-	if err2 != nil {
-		log.Println("No elements in the Persistence Storage")
-	} else {
-		log.Println("Found Elements in the Persistence Storage, restarting them ...")
-
-		// restart jobs from the persistent storage
-		// the worker func gets the jobgroup for that gid schedule
-		for _, gid := range keys {
-
-			var jg map[string]jobDetails
+	// schedule the workflows in the storage
+	err = registerWorkflows()
+	if err != nil {
+		return errors.Wrap(err, "Error Initializing grontab")
+	}
 
-			// get the tasks for the schedule
-			err := db.Get(grontabConfiguration.BucketName, gid, &jg)
-			if err != nil {
-				log.Panic("Error Getting object from storage for gid: " + gid)
-			}
+	// get the schedules from the storage
+	groups, err := loadJobGroups()
+	if err != nil {
+		return errors.Wrap(err, "Error Initializing grontab")
+	}
 
-			// generate the worker function that executes the tasks at this schedule (gid)
-			worker := workerFuncGen(gid)
+	if len(groups) == 0 {
+		log.Println("No elements in the Persistence Storage")
+		return nil
+	}
 
-			// generate a random unique id
-			rid, err := randid.ID()
-			if err != nil {
-				return err
-			}
-			ugid := fmt.Sprintf("%s", rid)
+	log.Println("Found Elements in the Persistence Storage, restarting them ...")
 
-			// add to the engine the worker function at this specific schetule
-			err = c.AddFunc(gid, worker, ugid)
+	// restart jobs from the persistent storage
+	// the worker func gets the jobgroup for that gid schedule
+	for gid, jobs := range groups {
+		// add to the engine the worker function at this specific schedule
+		for _, job := range jobs {
+			err = registerSchedule(gid, job.Schedule)
 			if err != nil {
 				log.Println(err)
+				break
 			}
-
-			// save the mapping gid-ugid in the table
-			ugidTable[gid] = ugid
 		}
 	}
 	return nil
@@ -175,243 +310,266 @@ func initialize(config Config) error {
 }
 
 func start() {
+	if grontabConfiguration.ReadOnly {
+		log.Println("The storage is read only, the engine is not started")
+		return
+	}
 	// startup a new cron routine
 	c.Start()
+	started = true
+	// run the one-shot jobs missed while down
+	runMissed()
+	// run the @startup jobs
+	runStartupJobs()
+	// arm the fixed-delay jobs
+	startDelayedJobs()
 }
 
-func add(jid string, gid string, task jobDetails) (string, error) {
-	// empty jobgroup to be filled
-	var jg map[string]jobDetails
-
-	// check if the schedule is already in the db
-	err := db.Get(grontabConfiguration.BucketName, gid, &jg)
-
-	// if err != nil means the gid schedule is new and not present in db
-	// so it is necessary to create a new jg and schedule and start a new AddFunc
-	if err != nil {
-		// new gid schedule, so initialize an empty jobgroup of this new gid
-		jg = make(map[string]jobDetails)
-
-		// this is a new gid, so a new schedule
-		// generate a func responsible to run that gid
-		worker := workerFuncGen(gid)
+func add(job Job) (string, error) {
+	// validate the schedule before touching the storage
+	if _, err := parseSchedule(job.Schedule); err != nil {
+		return "", errors.Wrap(err, "Error Adding schedule to grontab")
+	}
+	if err := validateResult(job); err != nil {
+		return "", errors.Wrap(err, "Error Adding job to grontab")
+	}
+	if err := validateActions(job); err != nil {
+		return "", errors.Wrap(err, "Error Adding job to grontab")
+	}
+	if err := job.Precondition.validate(); err != nil {
+		return "", errors.Wrap(err, "Error Adding job to grontab")
+	}
+	if err := job.Guard.validate(); err != nil {
+		return "", errors.Wrap(err, "Error Adding job to grontab")
+	}
+	if err := validateLocks(job); err != nil {
+		return "", errors.Wrap(err, "Error Adding job to grontab")
+	}
 
-		// and add that func to the cron routine
+	var taskKey string
+	taskAlreadyExists := false
 
-		// generate a random unique id
-		rid, err := randid.ID()
+	err := store.Update(func(tx Tx) error {
+		jobs, err := tx.Jobs()
 		if err != nil {
-			return "", err
+			return err
 		}
-		ugid := fmt.Sprintf("%s", rid)
 
-		// add to the engine the worker function at this specific schetule
-		err = c.AddFunc(gid, worker, ugid)
-		if err != nil {
-			return "", errors.Wrap(err, "Error Adding schedule to grontab")
+		// the referenced calendars must exist
+		if _, _, err := jobCalendars(tx, job); err != nil {
+			return err
 		}
 
-		// save the mapping gid-ugid in the table
-		ugidTable[gid] = ugid
-
-	}
-
-	// check if the task already exists at this specific gid
-	// to avoid double insertion
-	taskAlreadyExists := false
-	var taskKey string
-	for k, v := range jg {
-		if v.Task == task.Task || k == jid {
-			taskAlreadyExists = true
-			taskKey = k
-			break
+		// check if the task already exists at this specific gid
+		// to avoid double insertion, a job id is unique across the schedules
+		for _, j := range jobs {
+			if j.ID == job.ID && j.Schedule != job.Schedule {
+				return errors.New("job " + job.ID + " already present at ['" + j.Schedule + "'], Update moves it")
+			}
+			if j.Schedule == job.Schedule && (j.Task == job.Task || j.ID == job.ID) {
+				taskAlreadyExists = true
+				taskKey = j.ID
+				return nil
+			}
 		}
-	}
 
-	if !taskAlreadyExists {
 		// insert the job at its corresponding jid
 		// create a unique jid if not specified
-		if jid == "" {
+		if job.ID == "" {
 			rid, err := randid.ID()
 			if err != nil {
-				return "", err
+				return err
 			}
-			jid = fmt.Sprintf("%s", rid)
+			job.ID = fmt.Sprintf("%s", rid)
 		}
-
-		// update job details
-		jg[jid] = jobDetails{
-			Task:    task.Task,
-			Enabled: task.Enabled,
-		}
-
-		// rewrite the updated jobgroup into the storage
-		err := db.Set(grontabConfiguration.BucketName, gid, jg)
-		if err != nil {
-			// unable to add schedule in persistent storage
-			return "", errors.Wrap(err, "Error Adding schedule to grontab persistent storage")
+		// the first run of a fixed-delay job is a delay after its addition
+		if isDelay(job.Schedule) {
+			job.DelayedNext = nextDelayed(job, time.Now())
 		}
+		return tx.PutJob(job)
+	})
+	if err != nil {
+		// unable to add schedule in persistent storage
+		return "", errors.Wrap(err, "Error Adding schedule to grontab persistent storage")
+	}
 
-		log.Printf(green("ADD JOB : {%s %s enabled:%t} to ['%s']"), jid, task.Task, task.Enabled, gid)
+	if taskAlreadyExists {
+		// if the job is already present, log it, and do nothing
+		log.Printf("Job %s already Present at ['%s']\n", job.Task, job.Schedule)
+		return taskKey, nil
+	}
 
-		return jid, nil
+	// if this is a new gid, so a new schedule
+	// add a func responsible to run that gid to the cron routine
+	err = registerSchedule(jobSchedule(job), job.Schedule)
+	if err != nil {
+		return "", err
 	}
+	armDelayed(job)
+
+	log.Printf(green("ADD JOB : {%s %s enabled:%t} to ['%s']"), job.ID, job.Task, job.Enabled, job.Schedule)
 
-	// otherwise, if the job is already present, log it, and do nothing
-	log.Printf("Job %s already Present at ['%s']\n", task.Task, gid)
-	return taskKey, nil
+	return job.ID, nil
 }
 
 func remove(jid string) error {
-	// find corresponding schedule id (gid) for this job id
-	gid, exists, err := find(jid)
-	if err != nil {
-		return err
-	}
-	if exists {
-		// gets the jobgroup for that gid
-		var jg map[string]jobDetails
-		err := db.Get(grontabConfiguration.BucketName, gid, &jg)
-		if err != nil {
-			log.Panic("Error Getting object from storage")
-		}
-
-		// remove a job with the specified jid from the jobgroup with specified gid
-		toBeDeletedJob := jg[jid]
-		delete(jg, jid)
+	var toBeDeletedJob Job
 
-		// rewrite the updated jobgroup into the storage
-		err = db.Set(grontabConfiguration.BucketName, gid, jg)
+	err := store.Update(func(tx Tx) error {
+		var err error
+		toBeDeletedJob, err = tx.GetJob(jid)
 		if err != nil {
-			return errors.Wrap(err, "Unable to Remove job with jid: "+jid+" from grontab schedule gid "+gid)
+			return err
 		}
-
-		// cleanup schedules that are now empty, if any
-		err = garbageCollectSchedule(gid)
+		// the jobs run by workflows are kept
+		workflows, err := workflowsOf(tx, jid)
 		if err != nil {
 			return err
 		}
-		log.Printf(yellow("REM JOB : {%s %s enabled:%t} from ['%s']"), jid, toBeDeletedJob.Task, toBeDeletedJob.Enabled, gid)
+		if len(workflows) > 0 {
+			return errors.Errorf("the job is run by the workflows %s", strings.Join(workflows, ", "))
+		}
+		// remove the job with the specified jid
+		return tx.DeleteJob(jid)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to Remove job with jid: "+jid)
 	}
-	return nil
-}
-
-func update(jid string, schedule string, task jobDetails) error {
+	disarmDelayed(jid)
 
-	// find corresponding schedule id (gid) for this job id
-	gid, exist, err := find(jid)
+	// cleanup schedules that are now empty, if any
+	err = garbageCollectSchedule(jobSchedule(toBeDeletedJob))
 	if err != nil {
 		return err
 	}
+	log.Printf(yellow("REM JOB : {%s %s enabled:%t} from ['%s']"), jid, toBeDeletedJob.Task, toBeDeletedJob.Enabled, toBeDeletedJob.Schedule)
+	return nil
+}
 
-	if exist {
-		zjg := make(map[string]jobDetails)
-		// get the jobgroup for the already existing schedule (gid)
-		err := db.Get(grontabConfiguration.BucketName, gid, &zjg)
-		if err != nil {
-			return errors.Wrap(err, "Error Updating Job "+jid)
-		}
+func update(job Job) error {
 
-		// delete the old jid key from the jobgroup
-		delete(zjg, jid)
+	var gid string
 
-		// delete the jobgroup at the schedule (gid)
-		err = db.Delete(grontabConfiguration.BucketName, gid)
-		if err != nil {
-			return errors.Wrap(err, "Error Updating Job")
+	// move the job to the new schedule in a single transaction
+	err := store.Update(func(tx Tx) error {
+		// find corresponding schedule id (gid) for this job id
+		current, err := tx.GetJob(job.ID)
+		if err == ErrNotFound {
+			return errors.New(red("ERR Grontab: job.ID: '" + job.ID + "' non provided or doesn't exists"))
 		}
-
-		// set the  updated jobgroup without the old job at the schedule (gid)
-		err = db.Set(grontabConfiguration.BucketName, gid, zjg)
 		if err != nil {
-			return errors.Wrap(err, "Error Updating Job")
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		gid = jobSchedule(current)
+		// the runs are counted by grontab
+		job.RunCount = current.RunCount
+
+		// an empty schedule keeps the job at its current schedules
+		if job.Schedule == "" {
+			job.Schedule = current.Schedule
+			job.Schedules = current.Schedules
+		}
+		// a fixed-delay job keeps its next activation, unless it moves to another delay
+		if isDelay(job.Schedule) {
+			job.DelayedNext = current.DelayedNext
+			if job.Schedule != current.Schedule || job.DelayedNext.IsZero() {
+				job.DelayedNext = nextDelayed(job, time.Now())
+			}
 		}
 
-		// create a new empty jobgroup
-		njg := make(map[string]jobDetails)
-
-		// fill it with the actual jobgroup from the db
-		db.Get(grontabConfiguration.BucketName, schedule, &njg)
-
-		// update the actual jobgroup with the new task at the corresponding jid
-		njg[jid] = jobDetails{
-			Task:    task.Task,
-			Enabled: task.Enabled,
+		// validate the new schedule before touching the storage,
+		// so that the cron registration after the commit cannot fail
+		if _, err := parseSchedule(job.Schedule); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if err := validateResult(job); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if err := validateActions(job); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if err := job.Precondition.validate(); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if err := job.Guard.validate(); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if err := validateLocks(job); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
+		}
+		if _, _, err := jobCalendars(tx, job); err != nil {
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
 		}
 
-		// update the db with the new jobgroup containing the new jid with the new task
-		err = db.Set(grontabConfiguration.BucketName, schedule, njg)
+		err = tx.PutJob(job)
 		if err != nil {
-			return errors.Wrap(err, "Error Updating Job")
+			return errors.Wrap(err, "Error Updating Job "+job.ID)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// cleanup schedules that are now empty, if any
+	// the storage is now consistent, align the cron engine to it
+	newGid := jobSchedule(job)
+	if gid != newGid {
 		err = garbageCollectSchedule(gid)
 		if err != nil {
 			return err
 		}
+	}
+	err = registerSchedule(newGid, job.Schedule)
+	if err != nil {
+		return err
+	}
+	armDelayed(job)
 
-		// if the schedule is new start a new cron routine for it
-		if schedule != gid {
-			// add that func to the cron routine
-			worker := workerFuncGen(schedule)
-
-			// generate a random unique id
-			rid, err := randid.ID()
-			if err != nil {
-				return err
-			}
-			ugid := fmt.Sprintf("%s", rid)
-
-			// add to the engine the worker function at this specific schetule
-			c.AddFunc(schedule, worker, ugid)
-
-			// save the mapping gid-ugid in the table
-			ugidTable[schedule] = ugid
-		}
-
-		log.Printf(yellow("UPD JOB : {%s %s enabled:%t} to ['%s']"), jid, task.Task, task.Enabled, schedule)
+	log.Printf(yellow("UPD JOB : {%s %s enabled:%t} to ['%s']"), job.ID, job.Task, job.Enabled, job.Schedule)
 
-		// no errors return nil
-		return nil
-	}
-	return errors.New(red("ERR Grontab: job.ID: '" + jid + "' non provided or doesn't exists"))
+	// no errors return nil
+	return nil
 }
 
-func list() map[string][]Job {
+func list(filters []Filter) map[string][]Job {
 
 	// create an empty jobs map
 	jobs := make(map[string][]Job)
 
-	// get the keys of the schedules in the db
-	keys, err := getKeys()
-	if err != nil {
-		log.Println("No elements in the Persistence Storage")
-	} else {
-		// elements are present in the storage, so some schedule exists
-		// loop over the schedules (gid)
-		for _, gid := range keys {
+	var filter Filter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
 
-			// get the jobgroup for the corresponding schedule (gid)
-			var jg map[string]jobDetails
-			err := db.Get(grontabConfiguration.BucketName, gid, &jg)
-			if err != nil {
-				log.Panic("Error Getting object from storage for gid: " + gid)
-			}
+	// get the matching jobs in the storage sorted by schedule (gid)
+	matching, err := listJobs(filter)
+	if err != nil {
+		log.Println(err)
+		return jobs
+	}
 
-			// loop over jobs in the jobgroup and store them in the jobs map created
-			var scheduleJobs []Job
-			for k, v := range jg {
-				scheduleJobs = append(scheduleJobs, Job{ID: k, Task: v.Task, Enabled: v.Enabled})
-			}
-			jobs[gid] = scheduleJobs
-		}
+	// group them by schedule
+	for _, job := range matching {
+		jobs[job.Schedule] = append(jobs[job.Schedule], job)
 	}
 
 	// return the filled jobs map
 	return jobs
 }
 
+func history(jid string) ([]Run, error) {
+	var runs []Run
+	err := store.View(func(tx Tx) error {
+		var err error
+		runs, err = tx.Runs(jid)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Getting history of Job "+jid)
+	}
+	return runs, nil
+}
+
 // Generates the functions that will be executed at each cron schedule
 func workerFuncGen(gid string) func() {
 	// it returns a worker function
@@ -425,61 +583,97 @@ func workerFuncGen(gid string) func() {
 		jobGroupID := fmt.Sprintf("%s", rid)
 
 		log.Printf(green("RUNN JG(%s)[%s]"), jobGroupID, gid)
+		activation := time.Now()
 
 		// get the jobgroup for this schedule (gid)
-		var jg map[string]jobDetails
-		err = db.Get(grontabConfiguration.BucketName, gid, &jg)
+		jg, err := loadJobGroup(gid)
 		if err != nil {
-			log.Panic("Error Putting object in storage\n")
+			log.Panic("Error Getting object from storage for gid: " + gid)
 		}
+		// the jobs run by Order, then the ones with the highest priority first
+		sortGroup(jg)
 
 		var jobWaitGroup sync.WaitGroup
-		var taskWaitGroup sync.WaitGroup
+		// the slots of the jobs of the group running at once, if bounded
+		var slots chan struct{}
+		if limit := groupParallelism(); limit > 0 {
+			slots = make(chan struct{}, limit)
+		}
+		// the failed job with StopOnFailure that stops the jobs not started yet, if any
+		var stopMutex sync.Mutex
+		var stoppedBy string
 
 		// the worker func takes one job at a time from the jobgroup
-		for jid, task := range jg {
-
-			// split the task command in args ([]string)
-			args := strings.Fields(task.Task)
+		for _, job := range jg {
 
 			// if the task is enabled, proceed with executing it
-			if task.Enabled {
-				log.Printf(green("EXEC JG(%s)[%s][%s]: %s"), jobGroupID, gid, jid, task.Task)
-
-				// keep count of the go routines spawned with a wait group for parallelism enabling/disabling
-				jobWaitGroup.Add(1)
-				if grontabConfiguration.DisableParallelism {
-					taskWaitGroup.Add(1)
+			if job.Enabled {
+				// the jobs past their validity window or their runs expire
+				if job.expiredAt(activation) {
+					expireJob(job.ID)
+					continue
+				}
+				if activation.Before(job.NotBefore) {
+					continue
 				}
 
-				go func() {
+				// the days excluded by the calendars of the job are skipped, and recorded
+				if reason := skipReason(job, activation); reason != "" {
+					log.Printf(yellow("SKIP JG(%s)[%s][%s]: %s"), jobGroupID, gid, job.ID, reason)
+					recordRun(skippedRun(job, activation, reason))
+					continue
+				}
 
-					// execute the command
-					cmdOut, err := exec.Command(args[0], args[1:]...).CombinedOutput()
-					cleanOutput := strings.Replace(string(cmdOut), "\n", "", -1)
+				// wait for a slot if the parallelism is bounded
+				if slots != nil {
+					slots <- struct{}{}
+				}
 
-					if err != nil {
-						log.Printf(red("Error executing: %s --> %s --> args: %#v\n"), task.Task, err, args)
+				stopMutex.Lock()
+				stopped := stoppedBy
+				stopMutex.Unlock()
+				if stopped != "" {
+					reason := "job " + stopped + " failed earlier in the group"
+					log.Printf(yellow("SKIP JG(%s)[%s][%s]: %s"), jobGroupID, gid, job.ID, reason)
+					recordRun(skippedRun(job, activation, reason))
+					if slots != nil {
+						<-slots
 					}
+					continue
+				}
+
+				log.Printf(green("EXEC JG(%s)[%s][%s]: %s"), jobGroupID, gid, job.ID, job.Task)
+
+				// keep count of the go routines spawned with a wait group
+				jobWaitGroup.Add(1)
+
+				go func(job Job) {
+
+					// execute the command, keep track of it in the history and follow it up
+					run := runJob(job)
 
 					log.Printf(
 						cyan("OUTP JG(%s)[%s][%s]: %s"),
 						jobGroupID,
 						gid,
-						jid,
-						cleanOutput,
+						job.ID,
+						strings.Replace(run.Output, "\n", "", -1),
 					)
+					// a failure stops the jobs of the group not started yet
+					if job.StopOnFailure && run.Status == RunFailed {
+						stopMutex.Lock()
+						if stoppedBy == "" {
+							stoppedBy = job.ID
+						}
+						stopMutex.Unlock()
+					}
+
 					// keep count of the go routines spawned with a wait group for parallelism
 					jobWaitGroup.Done()
-					if grontabConfiguration.DisableParallelism {
-						taskWaitGroup.Done()
+					if slots != nil {
+						<-slots
 					}
-				}()
-
-				// keep count of the go routines spawned with a wait group for parallelism
-				if grontabConfiguration.DisableParallelism {
-					taskWaitGroup.Wait()
-				}
+				}(job)
 			}
 		}
 		// wait until the jobgroup is completed
@@ -488,85 +682,346 @@ func workerFuncGen(gid string) func() {
 	}
 }
 
+// sortGroup sorts the jobs of a schedule in the order they start: by Order, then by Priority,
+// the highest first, then by id
+func sortGroup(jobs []Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		switch {
+		case jobs[i].Order != jobs[j].Order:
+			return jobs[i].Order < jobs[j].Order
+		case jobs[i].Priority != jobs[j].Priority:
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
+
+// groupParallelism returns how many jobs of a schedule run at once, 0 if unbounded
+func groupParallelism() int {
+	if grontabConfiguration.DisableParallelism {
+		return 1
+	}
+	return grontabConfiguration.MaxGroupParallelism
+}
+
+// execute runs the task of a job and returns the resulting run
+func execute(job Job) Run {
+	return executeContext(context.Background(), job)
+}
+
+// executeContext runs the task of a job with the env environment variables added,
+// killed when ctx is done or after the timeout of the job, and returns the resulting run
+func executeContext(ctx context.Context, job Job, env ...string) Run {
+	run := Run{JobID: job.ID, Schedule: job.Schedule, Start: time.Now()}
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	rid, err := randid.ID()
+	if err != nil {
+		panic(err)
+	}
+	run.ID = fmt.Sprintf("%s", rid)
+
+	// split the task command in args ([]string)
+	args := strings.Fields(job.Task)
+	if len(args) == 0 {
+		run.End = time.Now()
+		run.Status = RunFailed
+		run.ExitCode = -1
+		run.Error = "empty task"
+		return run
+	}
+
+	// give the task the results of its inputs, and the file to write its own result to
+	inputs, args, err := resultInputs(job, args)
+	env = append(env, inputs...)
+	var resultFile string
+	if err == nil {
+		resultFile, err = outputFile(job)
+	}
+	if resultFile != "" {
+		env = append(env, outputEnv+"="+resultFile)
+	}
+	if err != nil {
+		run.End = time.Now()
+		run.Status = RunFailed
+		run.ExitCode = -1
+		run.Error = err.Error()
+		return run
+	}
+
+	// execute the command, keeping its standard output apart for the result
+	var combined, stdout bytes.Buffer
+	output := &lockedWriter{w: &combined}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = io.MultiWriter(output, &stdout)
+	cmd.Stderr = output
+	err = cmd.Run()
+	run.End = time.Now()
+	run.Output = combined.String()
+	run.Status = RunSucceeded
+	run.ExitCode = -1
+	if cmd.ProcessState != nil {
+		run.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	run.TimedOut = ctx.Err() == context.DeadlineExceeded
+	if job.Result == ResultStdout {
+		run.Result = strings.TrimSpace(stdout.String())
+	}
+
+	if resultFile != "" {
+		result, resultErr := readResult(resultFile)
+		run.Result = result
+		if err == nil {
+			err = resultErr
+		}
+	}
+
+	if err != nil {
+		log.Printf(red("Error executing: %s --> %s --> args: %#v\n"), job.Task, err, args)
+		run.Status = RunFailed
+		run.Error = err.Error()
+	}
+	return run
+}
+
+// lockedWriter serializes the writes of the standard output and error of a task to the same writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// skippedRun returns the run of a job skipped at t
+func skippedRun(job Job, t time.Time, reason string) Run {
+	rid, err := randid.ID()
+	if err != nil {
+		panic(err)
+	}
+	return Run{ID: fmt.Sprintf("%s", rid), JobID: job.ID, Schedule: job.Schedule, Start: t, End: t, Status: RunSkipped, Reason: reason}
+}
+
+// recordRun saves a run in the history, dropping the oldest runs of the job
+// beyond the configured history size
+func recordRun(run Run) {
+	size := grontabConfiguration.HistorySize
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+
+	err := store.Update(func(tx Tx) error {
+		err := tx.PutRun(run)
+		if err != nil {
+			return err
+		}
+		runs, err := tx.Runs(run.JobID)
+		if err != nil {
+			return err
+		}
+		for len(runs) > size {
+			err = tx.DeleteRun(runs[0].ID)
+			if err != nil {
+				return err
+			}
+			runs = runs[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(red("Error recording run %s of job %s: %s"), run.ID, run.JobID, err)
+	}
+}
+
 func stop() {
+	// run the @shutdown jobs while the storage is still open,
+	// not when grontab was only opened, like by the commands of the cli
+	if started {
+		stopStartupJobs()
+		stopDelayedJobs()
+		runShutdownJobs()
+		started = false
+	}
 	// stop the cron engine
 	c.Stop()
 	// close the storage
-	db.Close()
+	store.Close()
 }
 
-// return keys of all the elements inside a bucket
-func getKeys() ([]string, error) {
-
-	var keys []string
-	err := db.Bolt.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(grontabConfiguration.BucketName))
-		if b == nil {
-			return fmt.Errorf("No Storage bucket " + grontabConfiguration.BucketName + " found")
+// loadJobGroups returns the jobs in the storage grouped by the schedule they run at (gid)
+func loadJobGroups() (map[string][]Job, error) {
+	groups := make(map[string][]Job)
+	err := store.View(func(tx Tx) error {
+		jobs, err := tx.Jobs()
+		if err != nil {
+			return err
 		}
-		c := b.Cursor()
-		for k, _ := c.First(); k != nil; k, _ = c.Next() {
-			kstr := fmt.Sprintf("%s", k)
-			// ignores the storm_metadata key
-			if kstr != "__storm_metadata" {
-				keys = append(keys, kstr)
-			}
+		for _, job := range jobs {
+			gid := jobSchedule(job)
+			groups[gid] = append(groups[gid], job)
 		}
 		return nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
-	return keys, nil
+	return groups, nil
 }
 
-// find finds an element in the db
-func find(jid string) (string, bool, error) {
-	// get the keys of all the schedules in the storage
-	keys, err := getKeys()
+// loadJobGroup returns the jobs running at a schedule (gid), looked up by the schedules stored by its jobs
+func loadJobGroup(gid string) ([]Job, error) {
+	ugidMu.Lock()
+	schedules := append([]string(nil), gidSchedules[gid]...)
+	ugidMu.Unlock()
+	if len(schedules) == 0 {
+		schedules = []string{gid}
+	}
+
+	var group []Job
+	err := store.View(func(tx Tx) error {
+		for _, schedule := range schedules {
+			jobs, err := tx.JobsAt(schedule)
+			if err != nil {
+				return err
+			}
+			for _, job := range jobs {
+				if jobSchedule(job) == gid {
+					group = append(group, job)
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return "", false, errors.Wrap(err, "Error finding Job")
+		return nil, err
+	}
+	return group, nil
+}
 
+// it checks if a schedule is empty and in that case removes it from the scheduler
+func garbageCollectSchedule(gid string) error {
+	// get the jobgroup to check the schedule (gid)
+	group, err := loadJobGroup(gid)
+	if err != nil {
+		return errors.Wrap(err, "Error during garbage collection of potentially unused gid")
 	}
 
-	// foreach jobgroup in each schedule
-	var jg map[string]jobDetails
-	for _, gid := range keys {
+	if len(group) == 0 {
+		// the schedule is now empty from jobs, stop the running schedule (gid/ugid)
+		unregisterSchedule(gid)
+	}
+	return nil
+}
 
-		err := db.Get(grontabConfiguration.BucketName, gid, &jg)
-		if err != nil {
-			log.Panic("Error Getting object from storage for gid: " + gid)
+// setSchedules sets the schedules of a job, and its Schedule to their union
+func setSchedules(job *Job, schedules []string) error {
+	var normalized []string
+	for _, schedule := range schedules {
+		schedule = normalizeSchedule(schedule)
+		if schedule == "" {
+			return errors.New("empty schedule")
 		}
-		// foreach task in the jobgroup
-		for k := range jg {
-			// if the current id matches with the searched one, return
-			if jid == k {
-				return gid, true, nil
-			}
+		if !containsString(normalized, schedule) {
+			normalized = append(normalized, schedule)
 		}
+	}
 
+	switch len(normalized) {
+	case 0:
+		return errors.New("a job needs at least a schedule")
+	case 1:
+		job.Schedule = normalized[0]
+	default:
+		job.Schedule = Union(normalized...)
 	}
-	return "", false, nil
+	job.Schedules = normalized
+	return nil
 }
 
-// it checks if a schedule is empty and in that case delete it
-func garbageCollectSchedule(gid string) error {
-	// get the jobgroup for the schedule (gid)
-	var jgz map[string]jobDetails
-	err := db.Get(grontabConfiguration.BucketName, gid, &jgz)
+// jobSchedules returns the schedules of a job
+func jobSchedules(job Job) []string {
+	if len(job.Schedules) > 0 {
+		return job.Schedules
+	}
+	return []string{job.Schedule}
+}
+
+// normalizeSchedule collapses the spacing of a schedule,
+// so that equivalent schedules end up in the same jobgroup
+func normalizeSchedule(schedule string) string {
+	return strings.Join(strings.Fields(schedule), " ")
+}
+
+// registerSchedule adds to the cron engine the worker func of a schedule (gid), if not registered yet,
+// schedule is the one stored by a job running at it, where the worker looks its jobs up
+func registerSchedule(gid string, schedule string) error {
+	// the fixed-delay jobs are run by their own timers
+	if isDelay(gid) {
+		return nil
+	}
+
+	ugidMu.Lock()
+	defer ugidMu.Unlock()
+	if _, registered := ugidTable[gid]; registered {
+		if !containsString(gidSchedules[gid], schedule) {
+			gidSchedules[gid] = append(gidSchedules[gid], schedule)
+		}
+		return nil
+	}
+
+	// generate the worker function that executes the tasks at this schedule (gid)
+	worker := workerFuncGen(gid)
+
+	// generate a random unique id
+	rid, err := randid.ID()
 	if err != nil {
-		return errors.Wrap(err, "Error during garbage collection of potentially unused gid")
+		return err
 	}
+	ugid := fmt.Sprintf("%s", rid)
 
-	if len(jgz) == 0 {
-		// the schedule is now empty from jobs, remove it from the scheduler and the persistent storage
-		// stop the running schedule (gid/ugid)
-		c.Remove(ugidTable[gid])
-		// remove mapping from the ugidTable
-		delete(ugidTable, gid)
-		// remove schedule from the persistent storage
-		db.Delete(grontabConfiguration.BucketName, gid)
+	parsed, err := parseSchedule(gid)
+	if err != nil {
+		return errors.Wrap(err, "Error Adding schedule to grontab")
 	}
+
+	// add to the engine the worker function at this specific schedule
+	c.Schedule(parsed, cron.FuncJob(worker), ugid)
+
+	// save the mapping gid-ugid in the table
+	ugidTable[gid] = ugid
+	gidSchedules[gid] = []string{schedule}
 	return nil
 }
+
+// unregisterSchedule stops the running schedule (gid) and forgets its ugid
+func unregisterSchedule(gid string) {
+	ugidMu.Lock()
+	defer ugidMu.Unlock()
+	ugid, ok := ugidTable[gid]
+	if !ok {
+		return
+	}
+	c.Remove(ugid)
+	// remove mapping from the ugidTable
+	delete(ugidTable, gid)
+	delete(gidSchedules, gid)
+}
+
+// registeredSchedules returns the schedules (gid) registered in the cron engine
+func registeredSchedules() map[string]bool {
+	ugidMu.Lock()
+	defer ugidMu.Unlock()
+	registered := make(map[string]bool, len(ugidTable))
+	for gid := range ugidTable {
+		registered[gid] = true
+	}
+	return registered
+}
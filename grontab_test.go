@@ -4,7 +4,9 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestListJob(t *testing.T) {
@@ -13,7 +15,7 @@ func TestListJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -33,7 +35,7 @@ func TestAddJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -58,7 +60,7 @@ func TestAddExistingJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -78,6 +80,35 @@ func TestAddExistingJob(t *testing.T) {
 	}
 }
 
+func TestAddExistingIDAtOtherSchedule(t *testing.T) {
+
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+
+	Start()
+
+	_, err := Add("*/10 * * * * *", Job{ID: "x", Task: "echo 'x'", Enabled: true, MaxRuns: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	countRun("x")
+
+	if _, err := Add("*/20 * * * * *", Job{ID: "x", Task: "echo 'x'", Enabled: true}); err == nil {
+		t.Errorf("expected Add() to refuse an id already present at another schedule")
+	}
+	job, _ := getTestJob("x")
+	if job.Schedule != "*/10 * * * * *" || job.RunCount != 1 {
+		t.Errorf("expected the job to be left untouched, got %+v", job)
+	}
+	if issues, _ := Check(); len(issues) != 0 {
+		t.Errorf("expected Check() to find no issues, found %v", issues)
+	}
+}
+
 func TestAddMultipleJobs(t *testing.T) {
 
 	cleaningErr := os.Remove("./db.db")
@@ -85,7 +116,7 @@ func TestAddMultipleJobs(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 	Start()
 
 	var ids []string
@@ -142,7 +173,7 @@ func TestAddMultipleJobsWithoutParallelism(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", DisableParallelism: true, TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", DisableParallelism: true, TurnOffLogs: true, HideBanner: true})
 	Start()
 
 	var ids []string
@@ -199,7 +230,7 @@ func TestUpdateJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 	Start()
 
 	timing := "*/10 * * * * *"
@@ -237,7 +268,7 @@ func TestWrongUpdateJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 	Start()
 
 	timing := "*/10 * * * * *"
@@ -261,7 +292,7 @@ func TestRemoveJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -292,7 +323,7 @@ func TestRemoveNonExistingJob(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -304,13 +335,16 @@ func TestRemoveNonExistingJob(t *testing.T) {
 }
 
 func TestInitResumingJobs(t *testing.T) {
+	if testBackend == "memory" {
+		t.Skip("the memory store doesn't persist across restarts")
+	}
 
 	cleaningErr := os.Remove("./db.db")
 	if cleaningErr != nil && os.IsExist(cleaningErr) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -322,7 +356,7 @@ func TestInitResumingJobs(t *testing.T) {
 
 	Stop()
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 	Start()
 
 	grontabMap := List()
@@ -338,7 +372,7 @@ func TestWorkerFuncGen(t *testing.T) {
 		t.Errorf("Unable to cleanup test env, before test running")
 	}
 
-	Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
 
 	Start()
 
@@ -351,3 +385,128 @@ func TestWorkerFuncGen(t *testing.T) {
 	worker := workerFuncGen(timing)
 	worker()
 }
+
+func TestUpdateJobInvalidSchedule(t *testing.T) {
+
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	timing := "*/10 * * * * *"
+	idPing, err := Add(timing, Job{Task: "ping -c 4 8.8.8.8", Enabled: true})
+	if err != nil {
+		log.Println(err)
+	}
+
+	err = Update("not a schedule", Job{ID: idPing, Task: "echo 'ciaone'", Enabled: true})
+	if err == nil {
+		t.Errorf("expected Update() to throw an error due to an invalid schedule")
+	}
+
+	grontabMap := List()
+	if len(grontabMap) != 1 || grontabMap[timing][0].Task != "ping -c 4 8.8.8.8" {
+		t.Errorf("expected a failed Update() to leave the job untouched")
+	}
+}
+
+func TestAddNormalizesSchedule(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	_, err := Add("*/10 * * * * *", Job{Task: "echo 'a'", Enabled: true})
+	if err != nil {
+		log.Println(err)
+	}
+	_, err = Add("  */10   * * * *  * ", Job{Task: "echo 'b'", Enabled: true})
+	if err != nil {
+		log.Println(err)
+	}
+
+	grontabMap := List()
+	if len(grontabMap) != 1 || len(grontabMap["*/10 * * * * *"]) != 2 {
+		t.Errorf("expected equivalent schedules to share the same jobgroup, got %v", grontabMap)
+	}
+}
+
+func TestAddSchedules(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	schedules := []string{"0 0 8 * * 1-5", "0  0 12 * * 0,6"}
+	id, err := AddSchedules(schedules, Job{Task: "echo 'backup'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ugidTable) != 1 {
+		t.Errorf("expected a single cron entry for the job, got %v", ugidTable)
+	}
+
+	jobs, err := ListJobs(Filter{Schedule: "0 0 12 * * 0,6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != id || strings.Join(jobs[0].Schedules, "|") != "0 0 8 * * 1-5|0 0 12 * * 0,6" {
+		t.Fatalf("expected the job to keep its schedules, got %+v", jobs)
+	}
+
+	times, err := NextRuns(id, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range times {
+		weekend := at.Weekday() == time.Saturday || at.Weekday() == time.Sunday
+		if weekend && at.Hour() != 12 || !weekend && at.Hour() != 8 {
+			t.Errorf("expected the job at 08:00 on weekdays and 12:00 on weekends, got %s", at)
+		}
+	}
+
+	// schedules holding iCalendar rules are kept whole
+	rule := "DTSTART:20260101T090000Z RRULE:FREQ=WEEKLY;BYDAY=MO,FR"
+	err = UpdateSchedules([]string{"0 0 8 * * 1-5", rule}, Job{ID: id, Task: "echo 'backup'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseSchedule(Union("0 0 8 * * 1-5", rule)); err != nil {
+		t.Errorf("expected the union with a rule to be valid, got %v", err)
+	}
+
+	// an empty schedule keeps the current ones
+	err = Update("", Job{ID: id, Task: "echo 'backup'", Enabled: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ = ListJobs(Filter{})
+	if len(jobs) != 1 || len(jobs[0].Schedules) != 2 || jobs[0].Enabled {
+		t.Errorf("expected the job to keep its schedules, got %+v", jobs)
+	}
+
+	err = Update("0 0 9 * * *", Job{ID: id, Task: "echo 'backup'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ = ListJobs(Filter{})
+	if len(jobs) != 1 || jobs[0].Schedule != "0 0 9 * * *" || len(jobs[0].Schedules) != 0 {
+		t.Errorf("expected the job back to a single schedule, got %+v", jobs)
+	}
+	if len(ugidTable) != 1 {
+		t.Errorf("expected the previous schedules to be unregistered, got %v", ugidTable)
+	}
+
+	if _, err := AddSchedules(nil, Job{Task: "echo 'none'"}); err == nil {
+		t.Errorf("expected AddSchedules() to refuse a job without schedules")
+	}
+}
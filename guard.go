@@ -0,0 +1,159 @@
+package grontab
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the files the system pressure is read from
+var (
+	procLoadavg = "/proc/loadavg"
+	procMeminfo = "/proc/meminfo"
+)
+
+// the interval between the checks of a deferred run when the CheckInterval of its guard is not set
+const defaultGuardInterval = 10 * time.Second
+
+// Guard defines the system pressure a job doesn't start under, read from /proc/loadavg, /proc/meminfo
+// and the file system of DiskPath: the run is skipped right away, or deferred up to MaxWait
+type Guard struct {
+	// MaxLoad is the highest 1-minute load average
+	MaxLoad float64
+	// MinAvailableMemory is the lowest available memory, in bytes
+	MinAvailableMemory uint64
+	// MinFreeDisk is the lowest free space, in bytes, on the file system of DiskPath
+	DiskPath    string
+	MinFreeDisk uint64
+	// MaxWait, if set, defers the run until the pressure drops, checking it every CheckInterval (10s if not set),
+	// the run is skipped if the pressure is still high after MaxWait
+	MaxWait       time.Duration
+	CheckInterval time.Duration
+}
+
+// validate checks the thresholds of a guard
+func (g Guard) validate() error {
+	if g.MaxLoad < 0 {
+		return errors.New("the maximum load of the guard is negative")
+	}
+	if g.MinFreeDisk > 0 && g.DiskPath == "" {
+		return errors.New("the guard needs a DiskPath to check its free space")
+	}
+	if g.MaxWait < 0 || g.CheckInterval < 0 {
+		return errors.New("the waits of the guard are negative")
+	}
+	return nil
+}
+
+// pressure returns why the system is under too much pressure to start a job, empty if it isn't
+func (g Guard) pressure() string {
+	if g.MaxLoad > 0 {
+		load, err := loadAverage()
+		if err != nil {
+			return "guard: " + err.Error()
+		}
+		if load > g.MaxLoad {
+			return fmt.Sprintf("guard: load %.2f above %.2f", load, g.MaxLoad)
+		}
+	}
+	if g.MinAvailableMemory > 0 {
+		available, err := availableMemory()
+		if err != nil {
+			return "guard: " + err.Error()
+		}
+		if available < g.MinAvailableMemory {
+			return fmt.Sprintf("guard: available memory %d below %d bytes", available, g.MinAvailableMemory)
+		}
+	}
+	if g.MinFreeDisk > 0 {
+		free, err := freeDisk(g.DiskPath)
+		if err != nil {
+			return "guard: " + err.Error()
+		}
+		if free < g.MinFreeDisk {
+			return fmt.Sprintf("guard: free space %d on %s below %d bytes", free, g.DiskPath, g.MinFreeDisk)
+		}
+	}
+	return ""
+}
+
+// wait waits for the pressure to drop, up to MaxWait, and returns how long it waited,
+// and why the job can't start if the pressure is still high
+func (g Guard) wait(ctx context.Context, job Job) (time.Duration, string) {
+	start := time.Now()
+	reason := g.pressure()
+	if reason == "" || g.MaxWait <= 0 {
+		return 0, reason
+	}
+
+	interval := g.CheckInterval
+	if interval <= 0 {
+		interval = defaultGuardInterval
+	}
+	log.Printf(yellow("DEFR JOB : {%s %s} up to %s: %s"), job.ID, job.Task, g.MaxWait, reason)
+	deadline := start.Add(g.MaxWait)
+	for reason != "" && time.Now().Before(deadline) {
+		if remaining := time.Until(deadline); remaining < interval {
+			interval = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return time.Since(start), reason
+		case <-time.After(interval):
+		}
+		reason = g.pressure()
+	}
+	return time.Since(start), reason
+}
+
+// loadAverage returns the 1-minute load average
+func loadAverage() (float64, error) {
+	content, err := ioutil.ReadFile(procLoadavg)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error Reading the load average")
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, errors.New("Error Reading the load average: empty " + procLoadavg)
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error Reading the load average")
+	}
+	return load, nil
+}
+
+// availableMemory returns the memory available for starting new processes, in bytes
+func availableMemory() (uint64, error) {
+	file, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error Reading the available memory")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// like "MemAvailable:    5671164 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "Error Reading the available memory")
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrap(err, "Error Reading the available memory")
+	}
+	return 0, errors.New("Error Reading the available memory: no MemAvailable in " + procMeminfo)
+}
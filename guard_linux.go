@@ -0,0 +1,20 @@
+//go:build linux
+// +build linux
+
+package grontab
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// freeDisk returns the space available to unprivileged users on the file system of path, in bytes
+func freeDisk(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error Reading the free space of "+path)
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
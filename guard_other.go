@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package grontab
+
+import (
+	"github.com/pkg/errors"
+)
+
+// freeDisk is read only on linux
+func freeDisk(path string) (uint64, error) {
+	return 0, errors.New("Error Reading the free space of " + path + ": supported only on linux")
+}
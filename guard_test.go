@@ -0,0 +1,74 @@
+package grontab
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuards(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	if _, err := Add("0 0 0 1 1 *", Job{Task: "true", Guard: Guard{MinFreeDisk: 1}}); err == nil {
+		t.Errorf("expected Add() to refuse a free space guard without a path")
+	}
+
+	// the pressure is read from fake /proc files
+	dir := t.TempDir()
+	defer func(loadavg, meminfo string) {
+		procLoadavg, procMeminfo = loadavg, meminfo
+	}(procLoadavg, procMeminfo)
+	procLoadavg = filepath.Join(dir, "loadavg")
+	procMeminfo = filepath.Join(dir, "meminfo")
+	writeProc := func(path string, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProc(procLoadavg, "5.00 3.00 1.00 3/73 20788\n")
+	writeProc(procMeminfo, "MemTotal:       8000000 kB\nMemAvailable:    102400 kB\n")
+
+	cases := []struct {
+		guard  Guard
+		reason string
+	}{
+		{Guard{MaxLoad: 8}, ""},
+		{Guard{MaxLoad: 4}, "load 5.00 above 4.00"},
+		{Guard{MinAvailableMemory: 50 << 20}, ""},
+		{Guard{MinAvailableMemory: 200 << 20}, "available memory"},
+		{Guard{DiskPath: dir, MinFreeDisk: 1}, ""},
+		{Guard{DiskPath: dir, MinFreeDisk: 1 << 62}, "free space"},
+		{Guard{MaxLoad: 4, MaxWait: 100 * time.Millisecond, CheckInterval: 20 * time.Millisecond}, "load"},
+	}
+	for i, c := range cases {
+		run := runJob(Job{ID: "heavy", Task: "true", Guard: c.guard})
+		switch {
+		case c.reason == "" && run.Status != RunSucceeded:
+			t.Errorf("case %d: expected the job to run, got %+v", i, run)
+		case c.reason != "" && (run.Status != RunSkipped || !strings.Contains(run.Reason, c.reason)):
+			t.Errorf("case %d: expected the run to be skipped as '%s', got %+v", i, c.reason, run)
+		}
+	}
+
+	// a deferred run starts once the pressure drops
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		writeProc(procLoadavg, "0.50 3.00 1.00 3/73 20788\n")
+	}()
+	run := runJob(Job{ID: "heavy", Task: "true", Guard: Guard{MaxLoad: 4, MaxWait: 5 * time.Second, CheckInterval: 20 * time.Millisecond}})
+	if run.Status != RunSucceeded || !strings.HasPrefix(run.Reason, "deferred") {
+		t.Errorf("expected the deferred run to start, got %+v", run)
+	}
+	if runs, _ := History("heavy"); len(runs) != len(cases)+1 {
+		t.Errorf("expected the skipped and deferred runs to be recorded, got %v", runs)
+	}
+}
@@ -0,0 +1,136 @@
+package grontab
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// the bounds of the fields accepting H, in order,
+// the days of the month stop at 28 to exist in every month
+var hashBounds = []fieldBounds{
+	secondBounds,
+	minuteBounds,
+	hourBounds,
+	{name: domBounds.name, min: 1, max: 28},
+	monthBounds,
+	weekdayBounds,
+}
+
+// jobSchedule returns the schedule a job runs at (its gid),
+// with the H tokens and the @random seed derived from its ID
+func jobSchedule(job Job) string {
+	resolved, err := resolveSchedule(job.Schedule, job.ID)
+	if err != nil {
+		// left as is, parsing it reports the error
+		return job.Schedule
+	}
+	return resolved
+}
+
+// resolveSchedule replaces the H tokens of a schedule expression with values derived from id,
+// and seeds its @random schedules with id
+func resolveSchedule(expr string, id string) (string, error) {
+	expr = normalizeSchedule(expr)
+
+	if isComposite(expr) {
+		name, args, _ := splitComposite(expr)
+		resolved := make([]string, len(args))
+		for i, arg := range args {
+			resolved[i] = arg
+			// the arguments that aren't schedules, like the @random window and seed
+			if !compositeSchedules[name].scheduleArg(i) {
+				continue
+			}
+			var err error
+			if resolved[i], err = resolveSchedule(arg, id); err != nil {
+				return "", err
+			}
+		}
+		if name == "random" && len(resolved) == 2 && id != "" {
+			resolved = append(resolved, strconv.FormatUint(uint64(hashOf(id, "random")), 10))
+		}
+		return "@" + name + "(" + strings.Join(resolved, "; ") + ")", nil
+	}
+
+	if strings.HasPrefix(expr, "@") || isRuleSet(expr) || !strings.Contains(expr, "H") {
+		return expr, nil
+	}
+
+	fields := strings.Fields(expr)
+	for i := range fields {
+		if i >= len(hashBounds) || !strings.Contains(fields[i], "H") {
+			continue
+		}
+		var parts []string
+		for _, part := range strings.Split(fields[i], ",") {
+			resolved, err := resolveHash(part, hashBounds[i], id)
+			if err != nil {
+				return "", &FieldError{Field: hashBounds[i].name, Value: fields[i], Reason: err.Error()}
+			}
+			parts = append(parts, resolved)
+		}
+		fields[i] = strings.Join(parts, ",")
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// resolveHash resolves a range of a field with a H token:
+// "H", "H(a-b)", "H/n" and "H(a-b)/n"
+func resolveHash(part string, bounds fieldBounds, id string) (string, error) {
+	if !strings.HasPrefix(part, "H") {
+		return part, nil
+	}
+
+	rangeAndStep := strings.SplitN(part[1:], "/", 2)
+	low, high := bounds.min, bounds.max
+	if r := rangeAndStep[0]; r != "" {
+		if !strings.HasPrefix(r, "(") || !strings.HasSuffix(r, ")") {
+			return "", errors.Errorf("'%s' is not H, H(a-b), H/n or H(a-b)/n", part)
+		}
+		lowAndHigh := strings.SplitN(r[1:len(r)-1], "-", 2)
+		if len(lowAndHigh) != 2 {
+			return "", errors.Errorf("the range of '%s' must be like (a-b)", part)
+		}
+		var err error
+		if low, err = parseValue(lowAndHigh[0], bounds); err != nil {
+			return "", err
+		}
+		if high, err = parseValue(lowAndHigh[1], bounds); err != nil {
+			return "", err
+		}
+		if low > high {
+			return "", errors.Errorf("the range of '%s' begins after its end", part)
+		}
+	}
+
+	hash := int(hashOf(id, bounds.name) % (1 << 31))
+	if len(rangeAndStep) == 1 {
+		return strconv.Itoa(low + hash%(high-low+1)), nil
+	}
+
+	step, err := strconv.Atoi(rangeAndStep[1])
+	if err != nil || step < 1 {
+		return "", errors.Errorf("the step of '%s' must be a positive number", part)
+	}
+	// a step without a range covers the whole field, even the days after the 28th
+	if rangeAndStep[0] == "" && bounds.name == domBounds.name {
+		high = domBounds.max
+	}
+	// the first value is spread within the first step
+	span := step
+	if span > high-low+1 {
+		span = high - low + 1
+	}
+	return fmt.Sprintf("%d-%d/%d", low+hash%span, high, step), nil
+}
+
+// hashOf returns a stable hash of an id for a purpose, like a field name
+func hashOf(id string, purpose string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(id + "/" + purpose))
+	return h.Sum32()
+}
@@ -0,0 +1,120 @@
+package grontab
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestResolveSchedule(t *testing.T) {
+	resolved, err := resolveSchedule("H H(0-3) * * * *", "job-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := resolveSchedule("H H(0-3) * * * *", "job-a")
+	if resolved != again {
+		t.Errorf("expected the H tokens to resolve to the same values, got '%s' and '%s'", resolved, again)
+	}
+
+	schedule, err := parseSchedule(resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := schedule.Next(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if next.Minute() > 3 {
+		t.Errorf("expected '%s' to activate within the minutes 0-3, got %s", resolved, next)
+	}
+
+	other, _ := resolveSchedule("H H * * * *", "job-b")
+	resolved, _ = resolveSchedule("H H * * * *", "job-a")
+	if other == resolved {
+		t.Errorf("expected different jobs to be spread, both got '%s'", resolved)
+	}
+
+	for _, expr := range []string{"H(0-70) * * * * *", "Hx * * * * *", "0 H/0 * * * *"} {
+		_, err := resolveSchedule(expr, "job-a")
+		if _, ok := errors.Cause(err).(*FieldError); !ok {
+			t.Errorf("expected resolveSchedule('%s') to return a *FieldError, got %v", expr, err)
+		}
+	}
+}
+
+func TestRandomSchedule(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	times, err := PreviewSchedule("@random(3h; 0 0 1 * * *; 42)", from, 10, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 10 {
+		t.Fatalf("expected 10 activations, got %v", times)
+	}
+
+	offsets := make(map[time.Duration]bool)
+	for i, activation := range times {
+		start := time.Date(2026, time.January, i+1, 1, 0, 0, 0, time.UTC)
+		if activation.Before(start) || !activation.Before(start.Add(3*time.Hour)) {
+			t.Errorf("expected activation %d to be within 3h of %s, got %s", i, start, activation)
+		}
+		offsets[activation.Sub(start)] = true
+	}
+	if len(offsets) < 2 {
+		t.Errorf("expected the random time to be drawn again every day, got %v", times)
+	}
+
+	// the same seed gives the same times
+	again, _ := PreviewSchedule("@random(3h; 0 0 1 * * *; 42)", from, 10, "UTC")
+	for i := range times {
+		if !times[i].Equal(again[i]) {
+			t.Errorf("expected activation %d to be stable, got %s and %s", i, times[i], again[i])
+		}
+	}
+}
+
+func TestHashedJobs(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	for _, id := range []string{"job-a", "job-b"} {
+		_, err := Add("H H * * * *", Job{ID: id, Task: "echo '" + id + "'", Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ugidTable) != 2 {
+		t.Errorf("expected each job to run at its own schedule, got %v", ugidTable)
+	}
+
+	// the next runs reflect the resolved schedule of the job
+	resolved, _ := resolveSchedule("H H * * * *", "job-a")
+	times, err := NextRuns("job-a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 1 || fmt.Sprintf("%d %d * * * *", times[0].Second(), times[0].Minute()) != resolved {
+		t.Errorf("expected NextRuns() to follow '%s', got %v", resolved, times)
+	}
+
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected Check() to find no issues, found %v", issues)
+	}
+
+	err = Remove("job-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ugidTable) != 1 {
+		t.Errorf("expected the schedule of the removed job to be unregistered, got %v", ugidTable)
+	}
+}
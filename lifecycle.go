@@ -0,0 +1,148 @@
+package grontab
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the lifecycle schedules: "@startup", optionally delayed like "@startup 30s", and "@shutdown"
+const (
+	startupSchedule  = "@startup"
+	shutdownSchedule = "@shutdown"
+)
+
+// the time given to the @shutdown jobs when Config.ShutdownTimeout is not set
+const defaultShutdownTimeout = 30 * time.Second
+
+// the timers of the delayed @startup jobs, stopped by Stop
+var (
+	startupTimers   []*time.Timer
+	startupTimersMu sync.Mutex
+)
+
+// lifecycleSchedule runs its jobs at the start or at the stop of grontab, never on a time basis
+type lifecycleSchedule struct {
+	event string
+	delay time.Duration
+}
+
+// isLifecycle reports if a schedule expression is a lifecycle schedule
+func isLifecycle(expr string) bool {
+	return expr == startupSchedule || strings.HasPrefix(expr, startupSchedule+" ") || expr == shutdownSchedule
+}
+
+// parseLifecycle parses a lifecycle schedule expression
+func parseLifecycle(expr string) (lifecycleSchedule, error) {
+	if expr == shutdownSchedule {
+		return lifecycleSchedule{event: shutdownSchedule}, nil
+	}
+	s := lifecycleSchedule{event: startupSchedule}
+	if delay := strings.TrimPrefix(expr, startupSchedule); delay != "" {
+		var err error
+		s.delay, err = time.ParseDuration(strings.TrimSpace(delay))
+		if err != nil || s.delay < 0 {
+			return s, errors.New("the delay of @startup must be a duration, like 30s")
+		}
+	}
+	return s, nil
+}
+
+// Next never activates, the lifecycle jobs are run by Start and Stop
+func (s lifecycleSchedule) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// lifecycleJobs returns the enabled jobs of a lifecycle event, with their schedules
+func lifecycleJobs(event string) ([]Job, []lifecycleSchedule, error) {
+	var jobs []Job
+	var schedules []lifecycleSchedule
+	err := store.View(func(tx Tx) error {
+		enabled, err := tx.EnabledJobs()
+		if err != nil {
+			return err
+		}
+		for _, job := range enabled {
+			if !isLifecycle(job.Schedule) {
+				continue
+			}
+			s, err := parseLifecycle(job.Schedule)
+			if err != nil || s.event != event {
+				continue
+			}
+			jobs = append(jobs, job)
+			schedules = append(schedules, s)
+		}
+		return nil
+	})
+	return jobs, schedules, err
+}
+
+// runStartupJobs runs the @startup jobs, after their delay
+func runStartupJobs() {
+	jobs, schedules, err := lifecycleJobs(startupSchedule)
+	if err != nil {
+		log.Printf(red("Error getting the @startup jobs: %s"), err)
+		return
+	}
+
+	startupTimersMu.Lock()
+	defer startupTimersMu.Unlock()
+	for i, job := range jobs {
+		job := job
+		log.Printf(green("STRT JOB : {%s %s} in %s"), job.ID, job.Task, schedules[i].delay)
+		startupTimers = append(startupTimers, time.AfterFunc(schedules[i].delay, func() {
+			runJob(job)
+		}))
+	}
+}
+
+// stopStartupJobs cancels the delayed @startup jobs not started yet
+func stopStartupJobs() {
+	startupTimersMu.Lock()
+	defer startupTimersMu.Unlock()
+	for _, timer := range startupTimers {
+		timer.Stop()
+	}
+	startupTimers = nil
+}
+
+// shutdownTimeout returns the time given by Stop to the jobs running at shutdown
+func shutdownTimeout() time.Duration {
+	if grontabConfiguration.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return grontabConfiguration.ShutdownTimeout
+}
+
+// runShutdownJobs runs the @shutdown jobs in parallel, killing the ones
+// still running after Config.ShutdownTimeout
+func runShutdownJobs() {
+	jobs, _, err := lifecycleJobs(shutdownSchedule)
+	if err != nil {
+		log.Printf(red("Error getting the @shutdown jobs: %s"), err)
+		return
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	timeout := shutdownTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		log.Printf(yellow("STOP JOB : {%s %s} within %s"), job.ID, job.Task, timeout)
+		go func(job Job) {
+			defer wg.Done()
+			runJobContext(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
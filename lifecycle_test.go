@@ -0,0 +1,95 @@
+package grontab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLifecycleJobs(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true,
+		ShutdownTimeout: 200 * time.Millisecond})
+
+	for schedule, id := range map[string]string{"@startup": "warmup", "@startup 1h": "later", "@shutdown": "flush"} {
+		_, err := Add(schedule, Job{ID: id, Task: "echo '" + id + "'", Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := Add("@shutdown", Job{ID: "slow", Task: "sleep 5", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add("@startup soon", Job{Task: "echo 'soon'"}); err == nil {
+		t.Errorf("expected Add() to refuse an invalid @startup delay")
+	}
+
+	jobs := List()
+	if len(jobs["@startup"]) != 1 || len(jobs["@shutdown"]) != 2 {
+		t.Errorf("expected the lifecycle jobs to be listed, got %v", jobs)
+	}
+	if times, _ := NextRuns("warmup", 1); len(times) != 0 {
+		t.Errorf("expected the lifecycle jobs to have no time activations, got %v", times)
+	}
+
+	Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if runs, _ := History("warmup"); len(runs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the @startup job to run at Start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stopStartupJobs()
+	if runs, _ := History("later"); len(runs) != 0 {
+		t.Errorf("expected the delayed @startup job to wait for its delay, got %v", runs)
+	}
+
+	// the @shutdown jobs run within the timeout, the slow one is killed
+	start := time.Now()
+	runShutdownJobs()
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the @shutdown jobs to be bounded by the timeout, took %s", elapsed)
+	}
+	runs, _ := History("flush")
+	if len(runs) != 1 || runs[0].Status != RunSucceeded {
+		t.Errorf("expected the @shutdown job to run, got %v", runs)
+	}
+	runs, _ = History("slow")
+	if len(runs) != 1 || runs[0].Status != RunFailed {
+		t.Errorf("expected the slow @shutdown job to be killed, got %v", runs)
+	}
+
+	if description, _ := Explain("@startup 30s"); description != "30s after each start of grontab" {
+		t.Errorf("unexpected description '%s'", description)
+	}
+}
+
+func TestShutdownJobsNotStarted(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+
+	// the @shutdown jobs don't run when grontab is stopped without being started
+	ran := filepath.Join(t.TempDir(), "ran")
+	_, err := Add("@shutdown", Job{ID: "flush", Task: "touch " + ran, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Stop()
+	if _, err := os.Stat(ran); err == nil {
+		t.Errorf("expected the @shutdown job not to run without Start")
+	}
+}
@@ -0,0 +1,158 @@
+package grontab
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// Warning defines a likely mistake found by Lint in a schedule expression
+type Warning struct {
+	Code    string
+	Message string
+}
+
+// the codes of the warnings reported by Lint
+const (
+	WarningNeverFires  = "never-fires"
+	WarningEverySecond = "every-second"
+	WarningDSTGap      = "dst-gap"
+	WarningFiveFields  = "five-fields"
+)
+
+// Lint returns the likely mistakes in a schedule expression,
+// daylight saving time gaps are checked in the local time zone used by the engine
+func Lint(expr string) ([]Warning, error) {
+	return lint(expr, time.Now(), time.Local)
+}
+
+func lint(expr string, now time.Time, loc *time.Location) ([]Warning, error) {
+	schedule, err := parseSchedule(expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Parsing schedule")
+	}
+
+	var warnings []Warning
+	expr = normalizeSchedule(expr)
+	// the lifecycle schedules don't fire on a time basis
+	if isLifecycle(expr) {
+		return warnings, nil
+	}
+	fields := strings.Fields(expr)
+	// descriptors, composite schedules and recurrence sets have no cron fields
+	descriptor := strings.HasPrefix(expr, "@") || isRuleSet(expr)
+
+	if !descriptor && len(fields) == 5 {
+		warnings = append(warnings, Warning{
+			Code: WarningFiveFields,
+			Message: fmt.Sprintf("the expression has 5 fields, read as 'second minute hour day-of-month month': "+
+				"if it is a crontab expression, the 6 fields equivalent is '0 %s'", expr),
+		})
+	}
+
+	if schedule.Next(now.In(loc)).IsZero() {
+		warnings = append(warnings, Warning{
+			Code:    WarningNeverFires,
+			Message: "the expression never fires",
+		})
+		return warnings, nil
+	}
+
+	if !descriptor && (fields[0] == "*" || fields[0] == "?") {
+		warnings = append(warnings, Warning{
+			Code:    WarningEverySecond,
+			Message: "the seconds field is '" + fields[0] + "': the job fires every second while the other fields match",
+		})
+	}
+
+	// schedules running every hour keep running after the clock jumps forward,
+	// only the ones bound to specific hours lose their activations in the gap
+	var matches func(time.Time) bool
+	switch spec := schedule.(type) {
+	case *cron.SpecSchedule:
+		if spec.Hour&starBit == 0 {
+			matches = func(t time.Time) bool { return specMatches(spec, t) }
+		}
+	case *extendedSchedule:
+		if spec.Hour&starBit == 0 {
+			matches = spec.matches
+		}
+	}
+	if matches != nil {
+		for _, gap := range dstGaps(now, now.AddDate(1, 0, 0), loc) {
+			if at, ok := matchesBetween(matches, gap.start, gap.end); ok {
+				warnings = append(warnings, Warning{
+					Code: WarningDSTGap,
+					Message: fmt.Sprintf("the activation at %s falls in a daylight saving time gap of %s (%s to %s doesn't exist) and won't run as expected",
+						at.Format("2006-01-02 15:04:05"), loc, gap.start.Format("15:04:05"), gap.end.Format("15:04:05")),
+				})
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// wallClockGap is a wall clock interval skipped by a daylight saving time transition,
+// represented with UTC times
+type wallClockGap struct {
+	start, end time.Time
+}
+
+// dstGaps returns the wall clock intervals skipped in loc between from and to
+func dstGaps(from, to time.Time, loc *time.Location) []wallClockGap {
+	var gaps []wallClockGap
+	for t := from.Truncate(time.Hour); t.Before(to); t = t.Add(time.Hour) {
+		_, before := t.In(loc).Zone()
+		_, after := t.Add(time.Hour).In(loc).Zone()
+		if after <= before {
+			continue
+		}
+
+		// find the transition instant within the hour
+		low, high := t, t.Add(time.Hour)
+		for high.Sub(low) > time.Second {
+			mid := low.Add(high.Sub(low) / 2)
+			if _, offset := mid.In(loc).Zone(); offset == before {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+
+		start := high.Add(time.Duration(before) * time.Second).UTC()
+		gaps = append(gaps, wallClockGap{start: start, end: start.Add(time.Duration(after-before) * time.Second)})
+	}
+	return gaps
+}
+
+// matchesBetween returns the first wall clock second in [start, end) matched by a schedule
+func matchesBetween(matches func(time.Time) bool, start, end time.Time) (time.Time, bool) {
+	for t := start; t.Before(end); t = t.Add(time.Second) {
+		if matches(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// specMatches reports if every field of spec matches the wall clock time t
+func specMatches(spec *cron.SpecSchedule, t time.Time) bool {
+	if 1<<uint(t.Month())&spec.Month == 0 ||
+		1<<uint(t.Hour())&spec.Hour == 0 ||
+		1<<uint(t.Minute())&spec.Minute == 0 ||
+		1<<uint(t.Second())&spec.Second == 0 {
+		return false
+	}
+
+	// as in the cron engine, when both day fields are restricted either one matches
+	domMatch := 1<<uint(t.Day())&spec.Dom > 0
+	dowMatch := 1<<uint(t.Weekday())&spec.Dow > 0
+	if spec.Dom&starBit > 0 || spec.Dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,55 @@
+package grontab
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, rome)
+
+	cases := map[string][]string{
+		"0 30 6 * * *":      nil,
+		"0 0 0 30 2 *":      {WarningNeverFires},
+		"* * * * * *":       {WarningEverySecond},
+		"30 6 * * 1":        {WarningFiveFields},
+		"0 30 2 * * *":      {WarningDSTGap},
+		"0 30 2 * * 1-5":    nil,
+		"0 30 2 * * 0,6":    {WarningDSTGap},
+		"@every 1m":         nil,
+		"* 30 2 25-31 3 *":  {WarningEverySecond, WarningDSTGap},
+		"0 30 2 ? 3,10 0L":  {WarningDSTGap},
+		"0 30 2 L 3 ?":      nil,
+		"0 30 2 * 3 ? 2026": {WarningDSTGap},
+		"0 30 2 * 3 ? 2030": nil,
+	}
+	for expr, expected := range cases {
+		warnings, err := lint(expr, now, rome)
+		if err != nil {
+			t.Errorf("expected lint('%s') to succeed, got %v", expr, err)
+			continue
+		}
+		var codes []string
+		for _, w := range warnings {
+			codes = append(codes, w.Code)
+		}
+		if len(codes) != len(expected) {
+			t.Errorf("expected lint('%s') to report %v, got %v", expr, expected, warnings)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != expected[i] {
+				t.Errorf("expected lint('%s') to report %v, got %v", expr, expected, warnings)
+			}
+		}
+	}
+
+	_, err = Lint("not a schedule")
+	if err == nil {
+		t.Errorf("expected Lint() to refuse an invalid schedule")
+	}
+}
@@ -0,0 +1,116 @@
+package grontab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the interval between the attempts to take a lock file held by another process
+const lockPollInterval = 100 * time.Millisecond
+
+// lockKey matches the valid lock keys, usable as file names
+var lockKey = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// the locks of the engine by key, each one a semaphore of a single slot
+var (
+	locks   = make(map[string]chan struct{})
+	locksMu sync.Mutex
+)
+
+// heldLocks are the locks taken by a run, with their lock files
+type heldLocks struct {
+	keys  []string
+	files []*os.File
+}
+
+// validateLocks checks the lock keys and the lock timeout of a job
+func validateLocks(job Job) error {
+	for _, key := range job.Locks {
+		if !lockKey.MatchString(key) || key == "." || key == ".." {
+			return errors.Errorf("invalid lock key '%s', it can have only letters, digits, '_', '.' and '-'", key)
+		}
+	}
+	if job.LockTimeout < 0 {
+		return errors.New("the lock timeout is negative")
+	}
+	return nil
+}
+
+// lockOf returns the lock of a key
+func lockOf(key string) chan struct{} {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	lock, ok := locks[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		locks[key] = lock
+	}
+	return lock
+}
+
+// acquireLocks takes the locks of a job in the order of their keys, waiting up to the LockTimeout of the job,
+// and their lock files in Config.LockDir if set, it returns why they can't be taken, empty if they are
+func acquireLocks(ctx context.Context, job Job) (*heldLocks, string) {
+	held := &heldLocks{}
+	if len(job.Locks) == 0 {
+		return held, ""
+	}
+
+	keys := append([]string(nil), job.Locks...)
+	sort.Strings(keys)
+	deadline := time.Now().Add(job.LockTimeout)
+	for i, key := range keys {
+		// the same key listed twice is taken once
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+
+		lock := lockOf(key)
+		select {
+		case lock <- struct{}{}:
+		default:
+			timer := time.NewTimer(time.Until(deadline))
+			select {
+			case lock <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				held.release()
+				return nil, "lock " + key + " held by another job"
+			case <-ctx.Done():
+				timer.Stop()
+				held.release()
+				return nil, "lock " + key + " not taken: " + ctx.Err().Error()
+			}
+		}
+		held.keys = append(held.keys, key)
+
+		if grontabConfiguration.LockDir == "" {
+			continue
+		}
+		file, err := lockFile(ctx, filepath.Join(grontabConfiguration.LockDir, key+".lock"), deadline)
+		if err != nil {
+			held.release()
+			return nil, "lock " + key + " not taken: " + err.Error()
+		}
+		held.files = append(held.files, file)
+	}
+	return held, ""
+}
+
+// release releases the locks and the lock files, in the reverse order they were taken
+func (held *heldLocks) release() {
+	for i := len(held.files) - 1; i >= 0; i-- {
+		unlockFile(held.files[i])
+	}
+	for i := len(held.keys) - 1; i >= 0; i-- {
+		<-lockOf(held.keys[i])
+	}
+	held.files, held.keys = nil, nil
+}
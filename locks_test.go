@@ -0,0 +1,67 @@
+package grontab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocks(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	dir := t.TempDir()
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true, LockDir: dir})
+	Start()
+
+	if _, err := Add("0 0 0 1 1 *", Job{Task: "true", Locks: []string{"../db"}}); err == nil {
+		t.Errorf("expected Add() to refuse a lock key that isn't a file name")
+	}
+
+	// a long run holds the lock
+	done := make(chan Run)
+	go func() {
+		done <- runJob(Job{ID: "migrate", Task: "sleep 0.5", Locks: []string{"db", "cache"}})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	run := runJob(Job{ID: "backup", Task: "true", Locks: []string{"db"}})
+	if run.Status != RunSkipped || run.Reason != "lock db held by another job" {
+		t.Errorf("expected the run to be skipped while the lock is held, got %+v", run)
+	}
+	if run = runJob(Job{ID: "report", Task: "true", Locks: []string{"reports"}}); run.Status != RunSucceeded {
+		t.Errorf("expected the run with another lock not to wait, got %+v", run)
+	}
+	run = runJob(Job{ID: "backup", Task: "true", Locks: []string{"db"}, LockTimeout: 5 * time.Second})
+	migrate := <-done
+	if run.Status != RunSucceeded || run.Start.Before(migrate.End) {
+		t.Errorf("expected the run to wait for the lock, got %+v after %+v", run, migrate)
+	}
+
+	// the lock files are shared with the other processes
+	file, err := lockFile(context.Background(), filepath.Join(dir, "db.lock"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = runJob(Job{ID: "backup", Task: "true", Locks: []string{"db"}, LockTimeout: 200 * time.Millisecond})
+	if run.Status != RunSkipped || !strings.Contains(run.Reason, "held by another process") {
+		t.Errorf("expected the run to be skipped while the lock file is held, got %+v", run)
+	}
+	// the wait for a lock file ends with the context of the run, like at Stop
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	run = runJobContext(ctx, Job{ID: "backup", Task: "true", Locks: []string{"db"}, LockTimeout: 5 * time.Second})
+	if run.Status != RunSkipped || !strings.Contains(run.Reason, context.DeadlineExceeded.Error()) || time.Since(start) > time.Second {
+		t.Errorf("expected the run to stop waiting for the lock file with its context, got %+v", run)
+	}
+	unlockFile(file)
+	if run = runJob(Job{ID: "backup", Task: "true", Locks: []string{"db"}}); run.Status != RunSucceeded {
+		t.Errorf("expected the run once the lock file is released, got %+v", run)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+// +build !windows
+
+package grontab
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockFile takes an exclusive flock on the file at path, created if missing,
+// retrying until the deadline or until ctx is done
+func lockFile(ctx context.Context, path string, deadline time.Time) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Opening lock file")
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return file, nil
+		}
+		if err != syscall.EWOULDBLOCK || !time.Now().Before(deadline) {
+			file.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, errors.New(path + " held by another process")
+			}
+			return nil, errors.Wrap(err, "Error Locking "+path)
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// unlockFile releases the flock on a lock file and closes it
+func unlockFile(file *os.File) {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	file.Close()
+}
@@ -0,0 +1,22 @@
+//go:build windows
+// +build windows
+
+package grontab
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockFile is not supported on windows, the locks are kept only within grontab
+func lockFile(ctx context.Context, path string, deadline time.Time) (*os.File, error) {
+	return nil, errors.New("the lock files are not supported on windows")
+}
+
+// unlockFile closes a lock file
+func unlockFile(file *os.File) {
+	file.Close()
+}
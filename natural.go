@@ -0,0 +1,697 @@
+package grontab
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PhraseError defines an english schedule phrase that ParseNatural can't convert,
+// Unsupported lists the parts of the phrase that were not understood
+type PhraseError struct {
+	Phrase      string
+	Unsupported []string
+	Reason      string
+}
+
+func (e *PhraseError) Error() string {
+	msg := "Unsupported schedule phrase '" + e.Phrase + "'"
+	if len(e.Unsupported) > 0 {
+		msg += ": unsupported phrasing '" + strings.Join(e.Unsupported, "', '") + "'"
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// ParseNatural converts an english phrase like "every weekday at 9:30",
+// "every 15 minutes between 8am and 6pm" or "on the first Monday of each month at noon"
+// into a schedule expression, the windows cron can't express into an @between composite schedule
+func ParseNatural(phrase string) (string, error) {
+	return parseNatural(phrase)
+}
+
+// AddNatural adds a job at the schedule described by an english phrase, see ParseNatural
+func AddNatural(phrase string, job Job) (string, error) {
+	schedule, err := parseNatural(phrase)
+	if err != nil {
+		return "", err
+	}
+	return Add(schedule, job)
+}
+
+// the ordinals accepted before a weekday, e.g. "the second Tuesday"
+var ordinalValues = map[string]int{"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5}
+
+// the names of the ordinals, by value
+var ordinalNames = []string{"", "first", "second", "third", "fourth", "fifth"}
+
+// the words that only glue the phrase together
+var phraseFillers = map[string]bool{"on": true, "of": true, "the": true, "each": true,
+	"in": true, "and": true, "month": true, "a": true, "only": true}
+
+// the periods named by the adverbs
+var phrasePeriods = map[string]string{"weekly": "week", "monthly": "month", "yearly": "year", "annually": "year"}
+
+// phraseParser holds the schedule fields found in a phrase
+type phraseParser struct {
+	tokens      []string
+	pos         int
+	unsupported []string
+	lastFailed  int
+
+	// the frequency, from "every N units"
+	every int
+	unit  string
+	// the duration, from "every 1h30m"
+	interval time.Duration
+	// the time of the day, from "at 9:30"
+	at bool
+	// the window of the day, from "between 8am and 6pm", in seconds since midnight, to is excluded
+	windowed bool
+	from, to int
+
+	second, minute, hour  string
+	dom, month, dow, year []string
+	periodDefaults        []string
+
+	// why the phrase, although understood, can't be converted
+	reason string
+}
+
+func parseNatural(phrase string) (string, error) {
+	p := &phraseParser{tokens: tokenizePhrase(phrase), lastFailed: -2}
+	for p.pos < len(p.tokens) {
+		if !p.parseNext() {
+			p.fail()
+		}
+	}
+	if len(p.unsupported) > 0 {
+		return "", &PhraseError{Phrase: phrase, Unsupported: p.unsupported}
+	}
+
+	schedule, err := p.schedule()
+	if err != nil {
+		return "", &PhraseError{Phrase: phrase, Reason: err.Error()}
+	}
+	if _, err := parseSchedule(schedule); err != nil {
+		return "", &PhraseError{Phrase: phrase, Reason: err.Error()}
+	}
+	return schedule, nil
+}
+
+// tokenizePhrase splits a phrase into lowercase words
+func tokenizePhrase(phrase string) []string {
+	phrase = strings.ToLower(phrase)
+	phrase = strings.NewReplacer(",", " ", ";", " ", ".", " ").Replace(phrase)
+	return strings.Fields(phrase)
+}
+
+// peek returns the token k positions after the current one, "" past the end
+func (p *phraseParser) peek(k int) string {
+	if p.pos+k < len(p.tokens) {
+		return p.tokens[p.pos+k]
+	}
+	return ""
+}
+
+// fail records the current token as unsupported, merging it with the previous one if adjacent
+func (p *phraseParser) fail() {
+	if p.lastFailed == p.pos-1 && len(p.unsupported) > 0 {
+		p.unsupported[len(p.unsupported)-1] += " " + p.tokens[p.pos]
+	} else {
+		p.unsupported = append(p.unsupported, p.tokens[p.pos])
+	}
+	p.lastFailed = p.pos
+	p.pos++
+}
+
+// parseNext consumes the next part of the phrase, it reports false if it isn't understood
+func (p *phraseParser) parseNext() bool {
+	token := p.peek(0)
+	switch token {
+	case "every":
+		return p.parseEvery()
+	case "at":
+		return p.parseAt()
+	case "between", "from":
+		return p.parseWindow()
+	case "daily":
+		p.pos++
+		return true
+	case "hourly":
+		p.setEvery(1, "hour")
+		p.pos++
+		return true
+	case "weekly", "monthly", "yearly", "annually":
+		p.periodDefaults = append(p.periodDefaults, phrasePeriods[token])
+		p.pos++
+		return true
+	case "second", "minute":
+		if p.parseField() {
+			return true
+		}
+	case "day", "days":
+		return p.parseDays()
+	case "last":
+		return p.parseLast()
+	case "year", "years":
+		return p.parseYears()
+	}
+
+	if p.parseNearest() || p.parseBeforeLast() || p.parseOrdinal() || p.parseWeekdays() || p.parseMonths() || p.parseDayOfMonth() || p.parseYears() {
+		return true
+	}
+	if phraseFillers[token] {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseEvery parses "every N units", "every unit", "every 1h30m", "every day",
+// "every weekday", "every <weekday>" and "every week|month|year"
+func (p *phraseParser) parseEvery() bool {
+	next := p.peek(1)
+	if n, err := strconv.Atoi(next); err == nil && n > 0 {
+		if unit, ok := phraseUnit(p.peek(2)); ok {
+			p.setEvery(n, unit)
+			p.pos += 3
+			return true
+		}
+		return false
+	}
+	if unit, ok := phraseUnit(next); ok && !strings.HasSuffix(next, "s") {
+		p.setEvery(1, unit)
+		p.pos += 2
+		return true
+	}
+	if d, err := time.ParseDuration(next); err == nil && d > 0 {
+		p.interval = d
+		p.pos += 2
+		return true
+	}
+
+	switch next {
+	case "day", "night":
+		p.pos += 2
+		return true
+	case "week", "month", "year":
+		p.periodDefaults = append(p.periodDefaults, next)
+		p.pos += 2
+		return true
+	}
+	p.pos++
+	if p.parseWeekdays() {
+		return true
+	}
+	p.pos--
+	return false
+}
+
+// setEvery sets the frequency of the schedule
+func (p *phraseParser) setEvery(n int, unit string) {
+	p.every, p.unit = n, unit
+}
+
+// phraseUnit returns the time unit named by a word like "minutes" or "secs"
+func phraseUnit(word string) (string, bool) {
+	switch strings.TrimSuffix(word, "s") {
+	case "second", "sec":
+		return "second", true
+	case "minute", "min":
+		return "minute", true
+	case "hour", "hr":
+		return "hour", true
+	}
+	return "", false
+}
+
+// parseAt parses "at <time>", "at minute N" and "at second N"
+func (p *phraseParser) parseAt() bool {
+	p.pos++
+	if p.peek(0) == "minute" || p.peek(0) == "second" {
+		if p.parseField() {
+			return true
+		}
+	} else if h, m, s, ok := p.parseClock(); ok {
+		p.at = true
+		p.second, p.minute, p.hour = strconv.Itoa(s), strconv.Itoa(m), strconv.Itoa(h)
+		return true
+	}
+	p.pos--
+	return false
+}
+
+// parseField parses "minute N" and "second N"
+func (p *phraseParser) parseField() bool {
+	n, err := strconv.Atoi(p.peek(1))
+	if err != nil || n < 0 || n > 59 {
+		return false
+	}
+	if p.peek(0) == "minute" {
+		p.minute = strconv.Itoa(n)
+	} else {
+		p.second = strconv.Itoa(n)
+	}
+	p.pos += 2
+	return true
+}
+
+// parseWindow parses "between <time> and <time>" and "from <time> to|until <time>",
+// the window ends before the second time, it crosses midnight if it ends before it starts
+func (p *phraseParser) parseWindow() bool {
+	start := p.pos
+	p.pos++
+	fromHour, fromMinute, fromSecond, ok := p.parseClock()
+	if !ok {
+		p.pos = start
+		return false
+	}
+	switch p.peek(0) {
+	case "and", "to", "until", "till":
+		p.pos++
+	default:
+		p.pos = start
+		return false
+	}
+	toHour, toMinute, toSecond, ok := p.parseClock()
+	if !ok {
+		p.pos = start
+		return false
+	}
+
+	p.windowed = true
+	p.from = (fromHour*3600 + fromMinute*60 + fromSecond) % (24 * 3600)
+	p.to = (toHour*3600 + toMinute*60 + toSecond) % (24 * 3600)
+	return true
+}
+
+// parseClock parses a time of the day like "9", "9am", "9:30 pm", "09:30:15", "noon" or "midnight"
+func (p *phraseParser) parseClock() (int, int, int, bool) {
+	token := p.peek(0)
+	switch token {
+	case "noon", "midday":
+		p.pos++
+		return 12, 0, 0, true
+	case "midnight":
+		p.pos++
+		return 0, 0, 0, true
+	}
+
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(token, s) {
+			suffix, token = s, strings.TrimSuffix(token, s)
+		}
+	}
+	consumed := 1
+	if suffix == "" && (p.peek(1) == "am" || p.peek(1) == "pm") {
+		suffix, consumed = p.peek(1), 2
+	}
+
+	parts := strings.Split(token, ":")
+	if len(parts) > 3 {
+		return 0, 0, 0, false
+	}
+	values := []int{0, 0, 0}
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 || (i > 0 && (len(part) != 2 || v > 59)) {
+			return 0, 0, 0, false
+		}
+		values[i] = v
+	}
+
+	h := values[0]
+	switch {
+	case suffix != "" && (h < 1 || h > 12):
+		return 0, 0, 0, false
+	case suffix == "am" && h == 12:
+		h = 0
+	case suffix == "pm" && h != 12:
+		h += 12
+	case h > 24 || (h == 24 && (values[1] != 0 || values[2] != 0)):
+		return 0, 0, 0, false
+	}
+
+	p.pos += consumed
+	return h, values[1], values[2], true
+}
+
+// parseDays parses "day N" and "days N through M"
+func (p *phraseParser) parseDays() bool {
+	low, err := strconv.Atoi(p.peek(1))
+	if err != nil || low < 1 || low > 31 {
+		return false
+	}
+	switch p.peek(2) {
+	case "through", "to", "-":
+		high, err := strconv.Atoi(p.peek(3))
+		if err != nil || high < low || high > 31 {
+			return false
+		}
+		p.dom = append(p.dom, fmt.Sprintf("%d-%d", low, high))
+		p.pos += 4
+	default:
+		p.dom = append(p.dom, strconv.Itoa(low))
+		p.pos += 2
+	}
+	return true
+}
+
+// parseDayOfMonth parses an ordinal day of the month like "1st" or "15th"
+func (p *phraseParser) parseDayOfMonth() bool {
+	token := p.peek(0)
+	if len(token) < 3 {
+		return false
+	}
+	n, err := strconv.Atoi(token[:len(token)-2])
+	if err != nil || n < 1 || n > 31 {
+		return false
+	}
+	switch token[len(token)-2:] {
+	case "st", "nd", "rd", "th":
+		p.dom = append(p.dom, strconv.Itoa(n))
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseOrdinal parses an ordinal weekday of the month like "first Monday"
+func (p *phraseParser) parseOrdinal() bool {
+	nth, ok := ordinalValues[p.peek(0)]
+	if !ok {
+		return false
+	}
+	dow, ok := phraseWeekday(p.peek(1))
+	if !ok {
+		return false
+	}
+	p.dow = append(p.dow, fmt.Sprintf("%d#%d", dow, nth))
+	p.pos += 2
+	return true
+}
+
+// parseLast parses "last day", "last weekday" and "last <weekday>" of the month
+func (p *phraseParser) parseLast() bool {
+	switch next := p.peek(1); next {
+	case "day":
+		p.dom = append(p.dom, "L")
+	case "weekday":
+		p.dom = append(p.dom, "LW")
+	default:
+		dow, ok := phraseWeekday(next)
+		if !ok {
+			return false
+		}
+		p.dow = append(p.dow, strconv.Itoa(dow)+"L")
+	}
+	p.pos += 2
+	return true
+}
+
+// parseBeforeLast parses "N days before the last day" of the month
+func (p *phraseParser) parseBeforeLast() bool {
+	n, err := strconv.Atoi(p.peek(0))
+	if err != nil || (p.peek(1) != "days" && p.peek(1) != "day") || p.peek(2) != "before" ||
+		p.peek(3) != "the" || p.peek(4) != "last" || p.peek(5) != "day" {
+		return false
+	}
+	p.dom = append(p.dom, "L-"+strconv.Itoa(n))
+	p.pos += 6
+	return true
+}
+
+// parseNearest parses "weekday nearest to day N" and "nearest weekday to the Nth"
+func (p *phraseParser) parseNearest() bool {
+	switch p.peek(0) + " " + p.peek(1) + " " + p.peek(2) {
+	case "weekday nearest to", "nearest weekday to":
+	default:
+		return false
+	}
+	start := p.pos
+	p.pos += 3
+	if p.peek(0) == "the" {
+		p.pos++
+	}
+
+	// parse the day as any other day of the month, then mark it
+	days := len(p.dom)
+	parsed := p.parseDayOfMonth()
+	if !parsed && p.peek(0) == "day" {
+		parsed = p.parseDays()
+	}
+	if !parsed || strings.Contains(p.dom[days], "-") {
+		p.dom = p.dom[:days]
+		p.pos = start
+		return false
+	}
+	p.dom[days] += "W"
+	return true
+}
+
+// parseYears parses "year N", "years N through M" and a bare year like "2027"
+func (p *phraseParser) parseYears() bool {
+	start := p.pos
+	if p.peek(0) == "year" || p.peek(0) == "years" {
+		p.pos++
+	}
+	low, err := strconv.Atoi(p.peek(0))
+	if err != nil || low < yearBounds.min || low > yearBounds.max {
+		p.pos = start
+		return false
+	}
+	switch p.peek(1) {
+	case "through", "to", "-":
+		if high, err := strconv.Atoi(p.peek(2)); err == nil && high >= low && high <= yearBounds.max {
+			p.year = append(p.year, fmt.Sprintf("%d-%d", low, high))
+			p.pos += 3
+			return true
+		}
+	}
+	p.year = append(p.year, strconv.Itoa(low))
+	p.pos++
+	return true
+}
+
+// parseWeekdays parses a weekday or a range of weekdays like "monday through friday" or "mon-fri",
+// and "weekday(s)" and "weekend(s)"
+func (p *phraseParser) parseWeekdays() bool {
+	switch p.peek(0) {
+	case "weekday", "weekdays":
+		p.dow = append(p.dow, "1-5")
+		p.pos++
+		return true
+	case "weekend", "weekends":
+		p.dow = append(p.dow, "0", "6")
+		p.pos++
+		return true
+	}
+	return p.parseRange(phraseWeekday, &p.dow)
+}
+
+// parseMonths parses a month or a range of months like "january through march" or "jan-mar"
+func (p *phraseParser) parseMonths() bool {
+	return p.parseRange(phraseMonth, &p.month)
+}
+
+// parseRange parses a single value or a range of values named by words, like "mon-fri"
+func (p *phraseParser) parseRange(value func(string) (int, bool), field *[]string) bool {
+	if bounds := strings.SplitN(p.peek(0), "-", 2); len(bounds) == 2 {
+		low, lowOK := value(bounds[0])
+		high, highOK := value(bounds[1])
+		if !lowOK || !highOK {
+			return false
+		}
+		*field = append(*field, fmt.Sprintf("%d-%d", low, high))
+		p.pos++
+		return true
+	}
+
+	low, ok := value(p.peek(0))
+	if !ok {
+		return false
+	}
+	switch p.peek(1) {
+	case "through", "to", "-":
+		if high, ok := value(p.peek(2)); ok {
+			*field = append(*field, fmt.Sprintf("%d-%d", low, high))
+			p.pos += 3
+			return true
+		}
+	}
+	*field = append(*field, strconv.Itoa(low))
+	p.pos++
+	return true
+}
+
+// phraseWeekday returns the value of a weekday named like "monday", "mondays" or "mon"
+func phraseWeekday(word string) (int, bool) {
+	word = strings.TrimSuffix(word, "s")
+	for i, name := range weekdayNames {
+		if word == strings.ToLower(name) {
+			return i, true
+		}
+	}
+	v, ok := weekdayValues[word]
+	return v, ok
+}
+
+// phraseMonth returns the value of a month named like "january" or "jan"
+func phraseMonth(word string) (int, bool) {
+	for i, name := range monthNames {
+		if i > 0 && word == strings.ToLower(name) {
+			return i, true
+		}
+	}
+	v, ok := monthValues[word]
+	return v, ok
+}
+
+// schedule builds the schedule expression from the fields found in the phrase
+func (p *phraseParser) schedule() (string, error) {
+	if p.reason != "" {
+		return "", errors.New(p.reason)
+	}
+	if p.interval > 0 {
+		if p.at || p.windowed || p.every > 0 || p.second != "" || p.minute != "" ||
+			len(p.dom)+len(p.month)+len(p.dow)+len(p.year)+len(p.periodDefaults) > 0 {
+			return "", errors.Errorf("an interval of %s can't be combined with other conditions", p.interval)
+		}
+		return "@every " + p.interval.String(), nil
+	}
+
+	if p.at && (p.every > 0 || p.windowed) {
+		return "", errors.New("a time of the day can't be combined with a frequency or a window")
+	}
+
+	second, minute, hour := p.second, p.minute, p.hour
+	if p.every > 0 {
+		limit := map[string]int{"second": 60, "minute": 60, "hour": 24}[p.unit]
+		if p.every >= limit {
+			if p.windowed || second != "" || minute != "" ||
+				len(p.dom)+len(p.month)+len(p.dow)+len(p.year)+len(p.periodDefaults) > 0 {
+				return "", errors.Errorf("every %d %ss can't be combined with other conditions", p.every, p.unit)
+			}
+			return "@every " + (time.Duration(p.every) * map[string]time.Duration{
+				"second": time.Second, "minute": time.Minute, "hour": time.Hour}[p.unit]).String(), nil
+		}
+
+		step := "*"
+		if p.every > 1 {
+			step = "*/" + strconv.Itoa(p.every)
+		}
+		switch p.unit {
+		case "second":
+			if second != "" {
+				return "", errors.New("the second can't be set with a frequency in seconds")
+			}
+			second, minute = step, orDefault(minute, "*")
+		case "minute":
+			if minute != "" {
+				return "", errors.New("the minute can't be set with a frequency in minutes")
+			}
+			second, minute = orDefault(second, "0"), step
+		case "hour":
+			second, minute, hour = orDefault(second, "0"), orDefault(minute, "0"), step
+		}
+		hour = orDefault(hour, "*")
+	}
+
+	between := false
+	if p.windowed {
+		if p.every == 0 {
+			return "", errors.New("a window needs a frequency, e.g. 'every 15 minutes between 8am and 6pm'")
+		}
+		if p.from == p.to {
+			return "", errors.New("the window is empty")
+		}
+		// the windows of whole hours within a day are a range of hours,
+		// the other ones are kept by @between
+		to := p.to
+		if to == 0 {
+			to = 24 * 3600
+		}
+		if p.from%3600 == 0 && to%3600 == 0 && p.from < to {
+			window := fmt.Sprintf("%d-%d", p.from/3600, to/3600-1)
+			if strings.HasPrefix(hour, "*/") {
+				hour = window + strings.TrimPrefix(hour, "*")
+			} else {
+				hour = window
+			}
+		} else {
+			between = true
+		}
+	}
+
+	dom, month, dow := strings.Join(p.dom, ","), strings.Join(p.month, ","), strings.Join(p.dow, ",")
+	for _, period := range p.periodDefaults {
+		switch period {
+		case "week":
+			if dow == "" && dom == "" {
+				dow = "0"
+			}
+		case "month":
+			if dow == "" && dom == "" {
+				dom = "1"
+			}
+		case "year":
+			if dow == "" && dom == "" {
+				dom = "1"
+			}
+			if month == "" {
+				month = "1"
+			}
+		}
+	}
+
+	if second == "" && minute == "" && hour == "" {
+		if dom == "" && month == "" && dow == "" && len(p.year) == 0 {
+			return "", errors.New("no time or frequency found")
+		}
+		// on the days found, at midnight
+		second, minute, hour = "0", "0", "0"
+	}
+	second, minute, hour = orDefault(second, "0"), orDefault(minute, "0"), orDefault(hour, "*")
+	dom, month, dow = orDefault(dom, "*"), orDefault(month, "*"), orDefault(dow, "*")
+
+	fields := []string{second, minute, hour, dom, month, dow}
+	if len(p.year) > 0 {
+		fields = append(fields, strings.Join(p.year, ","))
+	}
+	if between {
+		return Between(clockOf(p.from), clockOf(p.to), strings.Join(fields, " ")), nil
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// clockOf returns the time of the day of the seconds since midnight, like "08:00" or "08:00:30"
+func clockOf(seconds int) string {
+	clock := fmt.Sprintf("%02d:%02d", seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		clock += fmt.Sprintf(":%02d", seconds%60)
+	}
+	return clock
+}
+
+// orDefault returns value, or def if value is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,121 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseNatural(t *testing.T) {
+	cases := map[string]string{
+		"every weekday at 9:30":                         "0 30 9 * * 1-5",
+		"every 15 minutes between 8am and 6pm":          "0 */15 8-17 * * *",
+		"on the first Monday of each month at noon":     "0 0 12 * * 1#1",
+		"on the last weekday of the month at 18:00":     "0 0 18 LW * *",
+		"on the weekday nearest to the 15th at 9am":     "0 0 9 15W * *",
+		"on the last friday of the month":               "0 0 0 * * 5L",
+		"every day at 9:30 only in 2027":                "0 30 9 * * * 2027",
+		"every 10 seconds":                              "*/10 * * * * *",
+		"hourly":                                        "0 0 * * * *",
+		"every hour at minute 30":                       "0 30 * * * *",
+		"every 2 hours between 8 and 18":                "0 0 8-17/2 * * *",
+		"every 90 minutes":                              "@every 1h30m0s",
+		"on the 1st and 15th of every month at 6pm":     "0 0 18 1,15 * *",
+		"every monday, wednesday and friday at 7:15 am": "0 15 7 * * 1,3,5",
+		"at 9:30 on weekends in january through march":  "0 30 9 * 1-3 0,6",
+		"monthly": "0 0 0 1 * *",
+		"every minute between 22:00 and midnight": "0 * 22-23 * * *",
+		// the windows cron can't express are kept by @between
+		"every 15 minutes between 10pm and 2am": "@between(22:00; 02:00; 0 */15 * * * *)",
+		"every 5 minutes from 9am to 5:30pm":    "@between(09:00; 17:30; 0 */5 * * * *)",
+	}
+	for phrase, expected := range cases {
+		schedule, err := ParseNatural(phrase)
+		if err != nil {
+			t.Errorf("expected ParseNatural('%s') to succeed, got %v", phrase, err)
+			continue
+		}
+		if schedule != expected {
+			t.Errorf("expected ParseNatural('%s') to be '%s', got '%s'", phrase, expected, schedule)
+			continue
+		}
+
+		// the explanation of the schedule converts back to the same schedule
+		description, err := Explain(schedule)
+		if err != nil {
+			t.Errorf("expected Explain('%s') to succeed, got %v", schedule, err)
+			continue
+		}
+		roundTrip, err := ParseNatural(description)
+		if err != nil || roundTrip != schedule {
+			t.Errorf("expected ParseNatural('%s') to be '%s', got '%s' (%v)", description, schedule, roundTrip, err)
+		}
+	}
+}
+
+func TestParseNaturalUnsupported(t *testing.T) {
+	_, err := ParseNatural("twice a day at lunchtime")
+	phraseErr, ok := err.(*PhraseError)
+	if !ok {
+		t.Fatalf("expected ParseNatural() to return a *PhraseError, got %v", err)
+	}
+	if len(phraseErr.Unsupported) != 2 || phraseErr.Unsupported[0] != "twice" || phraseErr.Unsupported[1] != "day at lunchtime" {
+		t.Errorf("expected the unsupported phrasing to be listed, got %v", phraseErr.Unsupported)
+	}
+
+	for _, phrase := range []string{"", "every 5 minutes from 9:00 to 9:00", "every day at 9:30 between 8 and 18"} {
+		_, err := ParseNatural(phrase)
+		if err == nil {
+			t.Errorf("expected ParseNatural('%s') to fail", phrase)
+		}
+	}
+}
+
+func TestIntersectSchedule(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	times, err := PreviewSchedule("@intersect(0 0 12 1-7 * *; 0 0 12 * * 1)", from, 3, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"2026-01-05T12:00:00Z", "2026-02-02T12:00:00Z", "2026-03-02T12:00:00Z"}
+	if len(times) != len(expected) {
+		t.Fatalf("expected %d activations, got %v", len(expected), times)
+	}
+	for i := range times {
+		if times[i].Format(time.RFC3339) != expected[i] {
+			t.Errorf("expected activation %d to be %s, got %s", i, expected[i], times[i].Format(time.RFC3339))
+		}
+	}
+
+	_, err = PreviewSchedule("@intersect(0 0 12 1-7 * *)", from, 3, "UTC")
+	if err == nil {
+		t.Errorf("expected @intersect of a single schedule to be refused")
+	}
+}
+
+func TestAddNatural(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	id, err := AddNatural("on the first Monday of each month at noon", Job{Task: "echo 'ciaone'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := ListJobs(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != id || jobs[0].Schedule != "0 0 12 * * 1#1" {
+		t.Errorf("expected the job to be added at the first Monday of the month, got %v", jobs)
+	}
+	if jobs[0].Next.IsZero() || jobs[0].Next.Weekday() != time.Monday || jobs[0].Next.Day() > 7 {
+		t.Errorf("expected the next activation to be on the first Monday of the month, got %s", jobs[0].Next)
+	}
+}
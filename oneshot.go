@@ -0,0 +1,228 @@
+package grontab
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// the prefix of the one-shot schedules, "@at 2026-10-18T09:00:00+02:00"
+const atPrefix = "@at "
+
+// atSchedule activates once, at its time
+type atSchedule struct {
+	at time.Time
+}
+
+// RunAt adds a Job running once at the given time, truncated to the second, it survives the restarts
+// and runs at Start if its time passed while grontab was down, unless the job has SkipMissed set,
+// a skipped run doesn't count: the job runs at the next Start like a missed one
+func RunAt(at time.Time, job Job) (string, error) {
+	return runAt(at, job)
+}
+
+func runAt(at time.Time, job Job) (string, error) {
+	// the schedule keeps the seconds, check the time cron will fire at
+	at = at.Truncate(time.Second)
+	if !at.After(time.Now()) {
+		return "", errors.New("Error Adding one-shot job: " + at.Format(time.RFC3339) + " is in the past")
+	}
+	job.MaxRuns = 1
+	return Add(atPrefix+at.Format(time.RFC3339), job)
+}
+
+// isAt reports if a schedule expression is a one-shot schedule
+func isAt(expr string) bool {
+	return strings.HasPrefix(expr, atPrefix)
+}
+
+// parseAt parses a one-shot schedule expression like "@at 2026-10-18T09:00:00+02:00"
+func parseAt(expr string) (atSchedule, error) {
+	at, err := time.Parse(time.RFC3339, strings.TrimPrefix(expr, atPrefix))
+	if err != nil {
+		return atSchedule{}, errors.New("the time of @at must be like 2026-10-18T09:00:00+02:00")
+	}
+	return atSchedule{at: at}, nil
+}
+
+// Next returns the time of the schedule if it is after t
+func (s atSchedule) Next(t time.Time) time.Time {
+	if s.at.After(t) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// windowSchedule keeps the activations of a schedule within NotBefore and NotAfter
+type windowSchedule struct {
+	schedule  cron.Schedule
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+// Next returns the first activation after t within the window
+func (s windowSchedule) Next(t time.Time) time.Time {
+	if !s.notBefore.IsZero() && t.Before(s.notBefore) {
+		t = s.notBefore.Add(-time.Second)
+	}
+	next := s.schedule.Next(t)
+	if !next.IsZero() && !s.notAfter.IsZero() && next.After(s.notAfter) {
+		return time.Time{}
+	}
+	return next
+}
+
+// remainingRuns returns how many more times a job runs, -1 if unbounded
+func (job Job) remainingRuns() int {
+	if job.MaxRuns <= 0 {
+		return -1
+	}
+	if job.RunCount >= job.MaxRuns {
+		return 0
+	}
+	return job.MaxRuns - job.RunCount
+}
+
+// expiredAt reports if a job can't run anymore at t, past NotAfter or its MaxRuns
+func (job Job) expiredAt(t time.Time) bool {
+	return job.remainingRuns() == 0 || !job.NotAfter.IsZero() && t.After(job.NotAfter)
+}
+
+// expireJob disables a job, or removes it if RemoveOnExpire is set
+func expireJob(jid string) {
+	var expired Job
+	removed := false
+	err := store.Update(func(tx Tx) error {
+		var err error
+		expired, err = tx.GetJob(jid)
+		if err != nil {
+			return err
+		}
+		if expired.RemoveOnExpire {
+			removed = true
+			return tx.DeleteJob(jid)
+		}
+		expired.Enabled = false
+		return tx.PutJob(expired)
+	})
+	if err != nil {
+		log.Printf(red("Error expiring job %s: %s"), jid, err)
+		return
+	}
+
+	if removed {
+		err = garbageCollectSchedule(jobSchedule(expired))
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	log.Printf(yellow("EXPD JOB : {%s %s removed:%t} at ['%s']"), jid, expired.Task, removed, expired.Schedule)
+}
+
+// countRun counts a run of a job, which expires when it reaches its MaxRuns
+func countRun(jid string) {
+	var job Job
+	err := store.Update(func(tx Tx) error {
+		var err error
+		job, err = tx.GetJob(jid)
+		if err != nil {
+			return err
+		}
+		job.RunCount++
+		return tx.PutJob(job)
+	})
+	if err != nil {
+		// the job may have been removed while running
+		log.Printf(red("Error counting the run of job %s: %s"), jid, err)
+		return
+	}
+	if job.Enabled && job.remainingRuns() == 0 {
+		expireJob(jid)
+	}
+}
+
+// runMissed runs the one-shot jobs whose time passed while grontab was down,
+// the ones with SkipMissed set expire without running
+func runMissed() {
+	var missed, skipped []Job
+	// the missed jobs are disabled in the transaction finding them, before their run starts,
+	// so the cron engine doesn't run them too
+	err := store.Update(func(tx Tx) error {
+		jobs, err := tx.EnabledJobs()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, job := range jobs {
+			if job.RunCount > 0 || !isAt(job.Schedule) {
+				continue
+			}
+			if s, err := parseAt(job.Schedule); err != nil || s.at.After(now) {
+				continue
+			}
+			if job.SkipMissed {
+				skipped = append(skipped, job)
+				if job.RemoveOnExpire {
+					err = tx.DeleteJob(job.ID)
+				} else {
+					err = disableJob(tx, job)
+				}
+			} else {
+				missed = append(missed, job)
+				err = disableJob(tx, job)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(red("Error getting the missed jobs: %s"), err)
+		return
+	}
+
+	for _, job := range skipped {
+		log.Printf(yellow("MISS JOB : {%s %s} at ['%s'], skipped"), job.ID, job.Task, job.Schedule)
+		if job.RemoveOnExpire {
+			if err := garbageCollectSchedule(jobSchedule(job)); err != nil {
+				log.Println(err)
+			}
+		}
+		log.Printf(yellow("EXPD JOB : {%s %s removed:%t} at ['%s']"), job.ID, job.Task, job.RemoveOnExpire, job.Schedule)
+	}
+	for _, job := range missed {
+		log.Printf(green("MISS JOB : {%s %s} at ['%s'], running it"), job.ID, job.Task, job.Schedule)
+		go runMissedJob(job)
+	}
+}
+
+// disableJob disables a job within a transaction
+func disableJob(tx Tx, job Job) error {
+	job.Enabled = false
+	return tx.PutJob(job)
+}
+
+// runMissedJob runs a missed one-shot job disabled by runMissed, a skipped run enables it again
+// to run at the next Start, any other run expires it
+func runMissedJob(job Job) {
+	run := runJob(job)
+	if run.Status != RunSkipped {
+		expireJob(job.ID)
+		return
+	}
+	err := store.Update(func(tx Tx) error {
+		current, err := tx.GetJob(job.ID)
+		if err != nil {
+			return err
+		}
+		current.Enabled = true
+		return tx.PutJob(current)
+	})
+	if err != nil {
+		log.Printf(red("Error enabling the missed job %s again: %s"), job.ID, err)
+	}
+}
@@ -0,0 +1,241 @@
+package grontab
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// getTestJob returns a job from the storage
+func getTestJob(id string) (Job, error) {
+	var job Job
+	err := store.View(func(tx Tx) error {
+		var err error
+		job, err = tx.GetJob(id)
+		return err
+	})
+	return job, err
+}
+
+func TestRunAt(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	if _, err := RunAt(time.Now().Add(-time.Minute), Job{Task: "echo 'late'"}); err == nil {
+		t.Errorf("expected RunAt() to refuse a time in the past")
+	}
+	// a time within the current second is in the past once truncated to the second
+	if _, err := RunAt(time.Now().Truncate(time.Second).Add(time.Second-time.Nanosecond), Job{Task: "echo 'late'"}); err == nil {
+		t.Errorf("expected RunAt() to refuse a time in the past once truncated")
+	}
+
+	at := time.Now().Add(time.Hour)
+	id, err := RunAt(at, Job{ID: "once", Task: "echo 'once'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times, err := NextRuns(id, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 1 || !times[0].Equal(at.Truncate(time.Second)) {
+		t.Errorf("expected a single run at %s, got %v", at, times)
+	}
+
+	// the activation runs the job once, then disables it
+	job, _ := getTestJob(id)
+	workerFuncGen(jobSchedule(job))()
+	job, _ = getTestJob(id)
+	runs, _ := History(id)
+	if len(runs) != 1 || job.RunCount != 1 || job.Enabled {
+		t.Errorf("expected the job to run once and be disabled, got %+v and %v", job, runs)
+	}
+	workerFuncGen(jobSchedule(job))()
+	if runs, _ = History(id); len(runs) != 1 {
+		t.Errorf("expected the job not to run again, got %v", runs)
+	}
+}
+
+func TestRunMissed(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+
+	// the jobs whose time passed while down
+	past := atPrefix + time.Now().Add(-time.Hour).Format(time.RFC3339)
+	err := store.Update(func(tx Tx) error {
+		err := tx.PutJob(Job{ID: "missed", Schedule: past, Task: "echo 'missed'", Enabled: true, MaxRuns: 1})
+		if err != nil {
+			return err
+		}
+		return tx.PutJob(Job{ID: "skipped", Schedule: past, Task: "echo 'skipped'", Enabled: true, MaxRuns: 1,
+			SkipMissed: true, RemoveOnExpire: true})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := getTestJob("missed")
+		if job.RunCount == 1 && !job.Enabled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the missed job to run at Start, got %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := getTestJob("skipped"); err != ErrNotFound {
+		t.Errorf("expected the missed job with SkipMissed to be removed, got %v", err)
+	}
+}
+
+func TestRunMissedOnce(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+
+	past := atPrefix + time.Now().Add(-time.Hour).Format(time.RFC3339)
+	err := store.Update(func(tx Tx) error {
+		return tx.PutJob(Job{ID: "missed", Schedule: past, Task: "sleep 0.5", Enabled: true, MaxRuns: 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Start()
+
+	// an activation of the schedule during the catch-up run doesn't run the job again
+	job, _ := getTestJob("missed")
+	if job.Enabled {
+		t.Errorf("expected the missed job to be disabled before its run, got %+v", job)
+	}
+	workerFuncGen(jobSchedule(job))()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ = getTestJob("missed")
+		if job.RunCount > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the missed job to run at Start, got %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	workerFuncGen(jobSchedule(job))()
+	runs, _ := History("missed")
+	if len(runs) != 1 || job.RunCount != 1 || job.Enabled {
+		t.Errorf("expected the missed job to run once and stay disabled, got %+v and %v", job, runs)
+	}
+}
+
+func TestValidityWindow(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	notBefore := time.Now().Add(48 * time.Hour)
+	_, err := Add("0 0 * * * *", Job{ID: "later", Task: "echo 'later'", Enabled: true, NotBefore: notBefore, MaxRuns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times, err := NextRuns("later", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 3 || times[0].Before(notBefore) {
+		t.Errorf("expected 3 runs after %s, got %v", notBefore, times)
+	}
+	// an activation before NotBefore doesn't run
+	workerFuncGen("0 0 * * * *")()
+	if runs, _ := History("later"); len(runs) != 0 {
+		t.Errorf("expected the job not to run before NotBefore, got %v", runs)
+	}
+
+	_, err = Add("*/10 * * * * *", Job{ID: "twice", Task: "echo 'twice'", Enabled: true, MaxRuns: 2, RemoveOnExpire: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workerFuncGen("*/10 * * * * *")()
+	workerFuncGen("*/10 * * * * *")()
+	if _, err := getTestJob("twice"); err != ErrNotFound {
+		t.Errorf("expected the job to be removed after MaxRuns, got %v", err)
+	}
+	if _, registered := ugidTable["*/10 * * * * *"]; registered {
+		t.Errorf("expected the schedule of the removed job to be unregistered")
+	}
+
+	_, err = Add("*/20 * * * * *", Job{ID: "ended", Task: "echo 'ended'", Enabled: true, NotAfter: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workerFuncGen("*/20 * * * * *")()
+	job, _ := getTestJob("ended")
+	runs, _ := History("ended")
+	if job.Enabled || len(runs) != 0 {
+		t.Errorf("expected the job past NotAfter to be disabled without running, got %+v and %v", job, runs)
+	}
+}
+
+func TestExpireWhileAdding(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	// the jobs expiring on the cron workers unregister their schedule while Add registers new ones
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		schedule := fmt.Sprintf("%d * * * * *", i)
+		_, err := Add(schedule, Job{ID: fmt.Sprintf("expiring-%d", i), Task: "echo 'expiring'", Enabled: true,
+			MaxRuns: 1, RemoveOnExpire: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerFuncGen(schedule)()
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		_, err := Add(fmt.Sprintf("%d 0 * * * *", i), Job{ID: fmt.Sprintf("added-%d", i), Task: "echo 'added'", Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected the cron engine to match the storage, got %+v", issues)
+	}
+	if registered := registeredSchedules(); len(registered) != 5 {
+		t.Errorf("expected only the schedules of the added jobs, got %v", registered)
+	}
+}
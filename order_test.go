@@ -0,0 +1,49 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGroupOrder(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true, DisableParallelism: true})
+	Start()
+
+	jobs := []Job{
+		{ID: "a", Task: "echo 'a'", Order: 2},
+		{ID: "b", Task: "echo 'b'", Order: 1},
+		{ID: "c", Task: "echo 'c'", Order: 1, Priority: 10},
+		{ID: "d", Task: "false", Order: 3, StopOnFailure: true},
+		{ID: "e", Task: "echo 'e'", Order: 4},
+	}
+	for _, job := range jobs {
+		job.Enabled = true
+		if _, err := Add("0 0 0 1 1 *", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	workerFuncGen("0 0 0 1 1 *")()
+
+	// c, b and a run one after the other, d fails and stops e
+	var previous Run
+	for _, id := range []string{"c", "b", "a", "d"} {
+		runs, _ := History(id)
+		if len(runs) != 1 || runs[0].Status == RunSkipped {
+			t.Fatalf("expected job %s to run, got %v", id, runs)
+		}
+		if runs[0].Start.Before(previous.End) {
+			t.Errorf("expected job %s to start after job %s ended", id, previous.JobID)
+		}
+		previous = runs[0]
+	}
+	runs, _ := History("e")
+	if len(runs) != 1 || runs[0].Status != RunSkipped || runs[0].Reason != "job d failed earlier in the group" {
+		t.Errorf("expected job e to be skipped after the failure of d, got %v", runs)
+	}
+}
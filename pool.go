@@ -0,0 +1,89 @@
+package grontab
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// the engine-wide pool bounding the jobs running at once, set up by Init
+var pool *workerPool
+
+// workerPool is a semaphore of size slots, handed to the waiting jobs by priority,
+// the jobs with the same priority in the order they arrived
+type workerPool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	waiting []poolWaiter
+}
+
+// poolWaiter is a job waiting for a slot of the pool
+type poolWaiter struct {
+	priority int
+	ready    chan struct{}
+}
+
+// newWorkerPool returns a pool of size slots, unbounded if size is not positive
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: size}
+}
+
+// acquire waits for a slot of the pool, it gives up when ctx is done
+func (p *workerPool) acquire(ctx context.Context, priority int) error {
+	if p == nil || p.size <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.running < p.size && len(p.waiting) == 0 {
+		p.running++
+		p.mu.Unlock()
+		return nil
+	}
+	waiter := poolWaiter{priority: priority, ready: make(chan struct{})}
+	// keep the waiters sorted, the next one first, after the ones with the same priority
+	i := sort.Search(len(p.waiting), func(i int) bool {
+		return p.waiting[i].priority < priority
+	})
+	p.waiting = append(p.waiting, poolWaiter{})
+	copy(p.waiting[i+1:], p.waiting[i:])
+	p.waiting[i] = waiter
+	p.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// leave the queue, unless the slot was handed over meanwhile: then hand it to the next one
+	p.mu.Lock()
+	for i := range p.waiting {
+		if p.waiting[i].ready == waiter.ready {
+			p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+			p.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	p.mu.Unlock()
+	p.release()
+	return ctx.Err()
+}
+
+// release hands the slot to the next waiting job, or frees it
+func (p *workerPool) release() {
+	if p == nil || p.size <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.waiting) == 0 {
+		p.running--
+		return
+	}
+	next := p.waiting[0]
+	p.waiting = p.waiting[1:]
+	close(next.ready)
+}
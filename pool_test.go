@@ -0,0 +1,143 @@
+package grontab
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolPriority(t *testing.T) {
+	p := newWorkerPool(1)
+	p.acquire(context.Background(), 0)
+
+	// the waiters queue up while the only slot is taken
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i, priority := range []int{1, 5, 3, 5} {
+		wg.Add(1)
+		go func(priority int) {
+			defer wg.Done()
+			p.acquire(context.Background(), priority)
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			p.release()
+		}(priority)
+		for {
+			p.mu.Lock()
+			queued := len(p.waiting)
+			p.mu.Unlock()
+			if queued == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	p.release()
+	wg.Wait()
+	expected := []int{5, 5, 3, 1}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected the waiters to start by priority %v, got %v", expected, order)
+		}
+	}
+	if p.running != 0 {
+		t.Errorf("expected the pool to be free, got %d running", p.running)
+	}
+}
+
+func TestGroupParallelism(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true,
+		MaxGroupParallelism: 2, MaxConcurrentJobs: 3})
+	Start()
+
+	// the tasks differ, or they would be the same job
+	for i, id := range []string{"a", "b", "c", "d"} {
+		if _, err := Add("0 0 0 1 1 *", Job{ID: id, Task: "sleep 0.3 0.00" + strconv.Itoa(i), Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 4 jobs, 2 at a time
+	start := time.Now()
+	workerFuncGen("0 0 0 1 1 *")()
+	if elapsed := time.Since(start); elapsed < 550*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("expected the jobs to run 2 at a time, took %s", elapsed)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if runs, _ := History(id); len(runs) != 1 || runs[0].Status != RunSucceeded {
+			t.Errorf("expected job %s to run, got %v", id, runs)
+		}
+	}
+}
+
+func TestWorkerPoolCancel(t *testing.T) {
+	p := newWorkerPool(1)
+	p.acquire(context.Background(), 0)
+
+	// a waiter gives up when its context is done, and leaves the queue
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.acquire(ctx, 0); err != context.DeadlineExceeded {
+		t.Errorf("expected acquire() to give up at the deadline, got %v", err)
+	}
+	if len(p.waiting) != 0 {
+		t.Errorf("expected the waiter to leave the queue, got %d waiting", len(p.waiting))
+	}
+	p.release()
+	if p.running != 0 {
+		t.Errorf("expected the pool to be free, got %d running", p.running)
+	}
+}
+
+func TestShutdownJobsPoolBound(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true,
+		MaxConcurrentJobs: 1, ShutdownTimeout: 200 * time.Millisecond})
+
+	if _, err := Add("@shutdown", Job{ID: "flush", Task: "echo 'flush'", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	// the only slot of the pool is taken by a long job
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runJob(Job{ID: "long", Task: "sleep 1"})
+	}()
+	for {
+		pool.mu.Lock()
+		running := pool.running
+		pool.mu.Unlock()
+		if running == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the @shutdown job doesn't wait for a slot past the timeout
+	start := time.Now()
+	runShutdownJobs()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the @shutdown jobs to be bounded by the timeout, took %s", elapsed)
+	}
+	runs, _ := History("flush")
+	if len(runs) != 1 || runs[0].Status != RunSkipped {
+		t.Errorf("expected the @shutdown job to be skipped, got %v", runs)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package grontab
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the time given to the command and the connection of a precondition when its Timeout is not set
+const defaultPreconditionTimeout = 10 * time.Second
+
+// Precondition defines the conditions checked before running the task of a job, all the ones set must hold,
+// otherwise the run is recorded as skipped with the reason
+type Precondition struct {
+	// FileExists is a path that must exist
+	FileExists string
+	// Command is a command that must exit zero
+	Command string
+	// Reachable is a host:port that must accept TCP connections
+	Reachable string
+	// LastSucceeded is a job whose last run must have succeeded
+	LastSucceeded string
+	// Timeout bounds the command and the connection, 10s if not set
+	Timeout time.Duration
+}
+
+// validate checks the command and the address of a precondition
+func (p Precondition) validate() error {
+	if p.Command != "" && strings.TrimSpace(p.Command) == "" {
+		return errors.New("the command of the precondition is empty")
+	}
+	if p.Reachable != "" {
+		if _, _, err := net.SplitHostPort(p.Reachable); err != nil {
+			return errors.Wrap(err, "the precondition must reach a host:port")
+		}
+	}
+	if p.Timeout < 0 {
+		return errors.New("the timeout of the precondition is negative")
+	}
+	return nil
+}
+
+// check returns why the precondition doesn't hold, empty if it holds
+func (p Precondition) check(ctx context.Context) string {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultPreconditionTimeout
+	}
+
+	if p.FileExists != "" {
+		if _, err := os.Stat(p.FileExists); err != nil {
+			return "precondition: " + p.FileExists + " doesn't exist"
+		}
+	}
+
+	if args := strings.Fields(p.Command); len(args) > 0 {
+		cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := exec.CommandContext(cmdCtx, args[0], args[1:]...).Run()
+		cancel()
+		if err != nil {
+			return "precondition: '" + p.Command + "' failed: " + err.Error()
+		}
+	}
+
+	if p.Reachable != "" {
+		conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", p.Reachable)
+		if err != nil {
+			return "precondition: " + p.Reachable + " not reachable: " + err.Error()
+		}
+		conn.Close()
+	}
+
+	if p.LastSucceeded != "" {
+		var last *Run
+		err := store.View(func(tx Tx) error {
+			runs, err := tx.Runs(p.LastSucceeded)
+			if err != nil {
+				return err
+			}
+			// the skipped runs didn't run
+			for i := len(runs) - 1; i >= 0; i-- {
+				if runs[i].Status != RunSkipped {
+					last = &runs[i]
+					break
+				}
+			}
+			return nil
+		})
+		switch {
+		case err != nil:
+			return "precondition: " + err.Error()
+		case last == nil:
+			return "precondition: job " + p.LastSucceeded + " never ran"
+		case last.Status != RunSucceeded:
+			return "precondition: the last run of job " + p.LastSucceeded + " " + string(last.Status)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package grontab
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPreconditions(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	if _, err := Add("0 0 0 1 1 *", Job{Task: "true", Precondition: Precondition{Reachable: "localhost"}}); err == nil {
+		t.Errorf("expected Add() to refuse a precondition address without a port")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	dir := t.TempDir()
+	cases := []struct {
+		precondition Precondition
+		reason       string
+	}{
+		{Precondition{}, ""},
+		{Precondition{FileExists: dir}, ""},
+		{Precondition{FileExists: dir + "/missing"}, "doesn't exist"},
+		{Precondition{Command: "true"}, ""},
+		{Precondition{Command: "false"}, "failed"},
+		{Precondition{Reachable: listener.Addr().String()}, ""},
+		{Precondition{Reachable: closed.Addr().String()}, "not reachable"},
+		{Precondition{LastSucceeded: "upstream"}, "never ran"},
+		{Precondition{FileExists: dir, Command: "false"}, "failed"},
+	}
+	for i, c := range cases {
+		job := Job{ID: "guarded", Task: "echo 'ciaone'", Precondition: c.precondition}
+		if err := job.Precondition.validate(); err != nil {
+			t.Fatal(err)
+		}
+		run := runJob(job)
+		switch {
+		case c.reason == "" && run.Status != RunSucceeded:
+			t.Errorf("case %d: expected the job to run, got %+v", i, run)
+		case c.reason != "" && (run.Status != RunSkipped || !strings.Contains(run.Reason, c.reason)):
+			t.Errorf("case %d: expected the run to be skipped as '%s', got %+v", i, c.reason, run)
+		}
+	}
+
+	// the last run of the upstream job decides
+	upstream := Job{ID: "upstream", Task: "false"}
+	runJob(upstream)
+	guarded := Job{ID: "guarded", Task: "echo 'ciaone'", Precondition: Precondition{LastSucceeded: "upstream"}}
+	if run := runJob(guarded); run.Status != RunSkipped || !strings.Contains(run.Reason, "failed") {
+		t.Errorf("expected the run to be skipped after a failed upstream run, got %+v", run)
+	}
+	upstream.Task = "true"
+	runJob(upstream)
+	if run := runJob(guarded); run.Status != RunSucceeded {
+		t.Errorf("expected the run after a succeeded upstream run, got %+v", run)
+	}
+
+	runs, _ := History("guarded")
+	skipped := 0
+	for _, run := range runs {
+		if run.Status == RunSkipped {
+			skipped++
+		}
+	}
+	if skipped != 6 {
+		t.Errorf("expected the 6 skipped runs in the history, got %d", skipped)
+	}
+}
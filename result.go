@@ -0,0 +1,123 @@
+package grontab
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// the sources of the result of a job
+const (
+	// ResultStdout makes the standard output of a run, trimmed, its result
+	ResultStdout = "stdout"
+	// ResultFile makes the JSON document written by a run to the file at $GRONTAB_OUTPUT its result
+	ResultFile = "file"
+)
+
+// the environment variable holding the path of the result file of a run
+const outputEnv = "GRONTAB_OUTPUT"
+
+// the prefix of the environment variables holding the results of the Inputs of a job,
+// followed by the upstream job id in upper case, like GRONTAB_RESULT_EXTRACT
+const resultEnvPrefix = "GRONTAB_RESULT_"
+
+// resultReference matches the references to the results of other jobs in the arguments of a task,
+// like "${result:extract}"
+var resultReference = regexp.MustCompile(`\$\{result:([^}]+)\}`)
+
+// envUnsafe matches the characters of a job id not allowed in an environment variable name
+var envUnsafe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// validateResult checks the result source of a job
+func validateResult(job Job) error {
+	switch job.Result {
+	case "", ResultStdout, ResultFile:
+		return nil
+	}
+	return errors.Errorf("invalid result source '%s', it must be '%s' or '%s'", job.Result, ResultStdout, ResultFile)
+}
+
+// resultEnvName returns the environment variable holding the result of a job
+func resultEnvName(jid string) string {
+	return resultEnvPrefix + envUnsafe.ReplaceAllString(strings.ToUpper(jid), "_")
+}
+
+// lastResult returns the result of the last succeeded run of a job, empty if none
+func lastResult(tx Tx, jid string) (string, error) {
+	runs, err := tx.Runs(jid)
+	if err != nil {
+		return "", err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Status == RunSucceeded {
+			return runs[i].Result, nil
+		}
+	}
+	return "", nil
+}
+
+// resultInputs returns the environment variables with the results of the Inputs of a job,
+// and the arguments of its task with the references to the results of other jobs replaced
+func resultInputs(job Job, args []string) ([]string, []string, error) {
+	var env []string
+	expanded := make([]string, len(args))
+	err := store.View(func(tx Tx) error {
+		for _, jid := range job.Inputs {
+			result, err := lastResult(tx, jid)
+			if err != nil {
+				return err
+			}
+			env = append(env, resultEnvName(jid)+"="+result)
+		}
+
+		var err error
+		for i, arg := range args {
+			expanded[i] = resultReference.ReplaceAllStringFunc(arg, func(reference string) string {
+				result, resultErr := lastResult(tx, resultReference.FindStringSubmatch(reference)[1])
+				if resultErr != nil {
+					err = resultErr
+				}
+				return result
+			})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error Getting the results of the inputs of job "+job.ID)
+	}
+	return env, expanded, nil
+}
+
+// outputFile creates the file a run of a job writes its result to, if the job takes it from a file
+func outputFile(job Job) (string, error) {
+	if job.Result != ResultFile {
+		return "", nil
+	}
+	file, err := ioutil.TempFile("", "grontab-output-")
+	if err != nil {
+		return "", errors.Wrap(err, "Error Creating the output file of job "+job.ID)
+	}
+	return file.Name(), file.Close()
+}
+
+// readResult reads the result of a run from its output file, which must hold a JSON document,
+// and removes the file
+func readResult(path string) (string, error) {
+	defer os.Remove(path)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "Error Reading the output file")
+	}
+	result := strings.TrimSpace(string(content))
+	if result == "" {
+		return "", nil
+	}
+	if !json.Valid([]byte(result)) {
+		return "", errors.New("the result written to $" + outputEnv + " is not a JSON document")
+	}
+	return result, nil
+}
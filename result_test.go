@@ -0,0 +1,73 @@
+package grontab
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestScript writes a shell script in a temporary directory and returns its path
+func writeTestScript(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+content+"\n"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResults(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	if _, err := Add("0 0 0 1 1 *", Job{Task: "echo 'ciaone'", Result: "stderr"}); err == nil {
+		t.Errorf("expected Add() to refuse an invalid result source")
+	}
+
+	jobs := []Job{
+		{ID: "count", Task: "echo 42", Result: ResultStdout},
+		{ID: "extract", Task: writeTestScript(t, "extract.sh", `echo '{"rows": 3}' > "$GRONTAB_OUTPUT"`), Result: ResultFile},
+		{ID: "broken", Task: writeTestScript(t, "broken.sh", `echo 'rows: 3' > "$GRONTAB_OUTPUT"`), Result: ResultFile},
+		{ID: "load", Task: "printenv GRONTAB_RESULT_EXTRACT", Inputs: []string{"extract"}},
+		{ID: "report", Task: "echo total:${result:count}"},
+	}
+	for _, job := range jobs {
+		if _, err := Add("0 0 0 1 1 *", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs := make(map[string]Run)
+	for _, job := range jobs {
+		job, _ = getTestJob(job.ID)
+		runs[job.ID] = execute(job)
+		recordRun(runs[job.ID])
+	}
+
+	if runs["count"].Result != "42" {
+		t.Errorf("expected the stdout result '42', got '%s'", runs["count"].Result)
+	}
+	if runs["extract"].Status != RunSucceeded || runs["extract"].Result != `{"rows": 3}` {
+		t.Errorf("expected the JSON document as result, got %+v", runs["extract"])
+	}
+	if runs["broken"].Status != RunFailed || runs["broken"].Result != "" {
+		t.Errorf("expected a result not in JSON to fail the run, got %+v", runs["broken"])
+	}
+	if strings.TrimSpace(runs["load"].Output) != `{"rows": 3}` {
+		t.Errorf("expected the upstream result in the environment, got %+v", runs["load"])
+	}
+	if strings.TrimSpace(runs["report"].Output) != "total:42" {
+		t.Errorf("expected the upstream result in the arguments, got %+v", runs["report"])
+	}
+
+	if history, _ := History("count"); len(history) != 1 || history[0].Result != "42" {
+		t.Errorf("expected the result to be kept with the run, got %v", history)
+	}
+}
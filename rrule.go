@@ -0,0 +1,683 @@
+package grontab
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the maximum number of periods of a rule visited looking for its next occurrence,
+// after them the rule is considered exhausted
+const maxRulePeriods = 100000
+
+// the frequencies of a recurrence rule, from the shortest
+const (
+	secondly = iota
+	minutely
+	hourly
+	daily
+	weekly
+	monthly
+	yearly
+)
+
+// the FREQ values of a recurrence rule, by frequency
+var ruleFrequencies = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+// the names of the frequencies in english, by frequency
+var ruleUnits = []string{"second", "minute", "hour", "day", "week", "month", "year"}
+
+// the weekdays of BYDAY and WKST
+var ruleWeekdays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday,
+	"WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+// the layouts of the date and date-time values
+const (
+	icalDate      = "20060102"
+	icalDateTime  = "20060102T150405"
+	icalUTCSuffix = "Z"
+)
+
+// ruleWeekday is a BYDAY value, n is the ordinal within the month or the year (0 for every one)
+type ruleWeekday struct {
+	weekday time.Weekday
+	n       int
+}
+
+// recurrenceRule is an RFC 5545 RRULE (or EXRULE)
+type recurrenceRule struct {
+	freq     int
+	interval int
+	count    int
+	until    time.Time
+
+	bySecond, byMinute, byHour     []int
+	byMonthDay, byYearDay, byMonth []int
+	bySetPos                       []int
+	byDay                          []ruleWeekday
+	weekStart                      time.Weekday
+	dtstart                        time.Time
+	// the start of the first period
+	base time.Time
+}
+
+// ruleSet is an RFC 5545 recurrence set: the occurrences of its RRULEs and RDATEs,
+// except the ones of its EXRULEs and EXDATEs, from DTSTART in its time zone
+type ruleSet struct {
+	dtstart time.Time
+	rrules  []*recurrenceRule
+	exrules []*recurrenceRule
+	rdates  []time.Time
+	exdates []time.Time
+	// the days excluded by the EXDATEs with VALUE=DATE, as "20060102"
+	exdays map[string]bool
+}
+
+// isRuleSet reports if a schedule expression is an iCalendar recurrence set
+func isRuleSet(expr string) bool {
+	return strings.HasPrefix(expr, "DTSTART") || strings.HasPrefix(expr, "RRULE:")
+}
+
+// parseRuleSet parses a recurrence set written as iCalendar content lines, separated by spaces or new lines:
+// "DTSTART;TZID=Europe/Rome:20260106T090000 RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20261231T235959Z"
+func parseRuleSet(expr string) (*ruleSet, error) {
+	s := &ruleSet{exdays: make(map[string]bool)}
+	var rrules, exrules []string
+
+	lines := strings.Fields(expr)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "DTSTART") {
+			name, params, value, err := splitContentLine(line)
+			if err != nil {
+				return nil, err
+			}
+			times, _, err := parseDateTimes(name, params, value)
+			if err != nil {
+				return nil, err
+			}
+			s.dtstart = times[0]
+		}
+	}
+	if s.dtstart.IsZero() {
+		return nil, errors.New("Invalid recurrence set: DTSTART is required")
+	}
+
+	for _, line := range lines {
+		name, params, value, err := splitContentLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "DTSTART":
+		case "RRULE":
+			rrules = append(rrules, value)
+		case "EXRULE":
+			exrules = append(exrules, value)
+		case "RDATE":
+			times, dates, err := parseDateTimes(name, params, value)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range times {
+				if dates {
+					// the dates take the time of the day of DTSTART
+					t = wallClock(t.Year(), t.Month(), t.Day(), s.dtstart.Hour(), s.dtstart.Minute(), s.dtstart.Second(), s.dtstart.Location())
+				}
+				s.rdates = append(s.rdates, t)
+			}
+		case "EXDATE":
+			times, dates, err := parseDateTimes(name, params, value)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range times {
+				if dates {
+					s.exdays[t.Format(icalDate)] = true
+				} else {
+					s.exdates = append(s.exdates, t)
+				}
+			}
+		default:
+			return nil, errors.Errorf("Invalid recurrence set: unsupported property '%s'", name)
+		}
+	}
+	if len(rrules) == 0 && len(s.rdates) == 0 {
+		return nil, errors.New("Invalid recurrence set: at least an RRULE or an RDATE is required")
+	}
+
+	for _, value := range rrules {
+		rule, err := parseRecurrenceRule("RRULE", value, s.dtstart)
+		if err != nil {
+			return nil, err
+		}
+		s.rrules = append(s.rrules, rule)
+	}
+	for _, value := range exrules {
+		rule, err := parseRecurrenceRule("EXRULE", value, s.dtstart)
+		if err != nil {
+			return nil, err
+		}
+		s.exrules = append(s.exrules, rule)
+	}
+	sort.Slice(s.rdates, func(i, j int) bool { return s.rdates[i].Before(s.rdates[j]) })
+	return s, nil
+}
+
+// splitContentLine splits a content line like "EXDATE;TZID=Europe/Rome:20260106T090000"
+// into its name, its parameters and its value
+func splitContentLine(line string) (string, map[string]string, string, error) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", errors.Errorf("Invalid recurrence set: '%s' is not like NAME[;PARAM=VALUE]:VALUE", line)
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", errors.Errorf("Invalid recurrence set: invalid parameter '%s' of %s", param, parts[0])
+		}
+		params[strings.ToUpper(kv[0])] = kv[1]
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+// parseDateTimes parses the comma separated dates or date-times of a property,
+// in the time zone of its TZID parameter, in UTC with a trailing Z, local otherwise,
+// it reports if the values are dates
+func parseDateTimes(name string, params map[string]string, value string) ([]time.Time, bool, error) {
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		var err error
+		loc, err = time.LoadLocation(tzid)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "Invalid recurrence set: invalid TZID of "+name)
+		}
+	}
+
+	var times []time.Time
+	dates := params["VALUE"] == "DATE"
+	for _, v := range strings.Split(value, ",") {
+		t, isDate, err := parseDateTime(v, loc)
+		if err != nil {
+			return nil, false, errors.Errorf("Invalid recurrence set: invalid %s value '%s'", name, v)
+		}
+		dates = dates || isDate
+		times = append(times, t)
+	}
+	return times, dates, nil
+}
+
+// parseDateTime parses a date ("20060102") or a date-time ("20060102T150405", "20060102T150405Z")
+// in loc, it reports if the value is a date
+func parseDateTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if len(value) == len(icalDate) {
+		t, err := time.ParseInLocation(icalDate, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, icalUTCSuffix) {
+		t, err := time.ParseInLocation(icalDateTime, strings.TrimSuffix(value, icalUTCSuffix), time.UTC)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation(icalDateTime, value, loc)
+	return t, false, err
+}
+
+// parseRecurrenceRule parses the value of an RRULE or an EXRULE like "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"
+func parseRecurrenceRule(name string, value string, dtstart time.Time) (*recurrenceRule, error) {
+	r := &recurrenceRule{freq: -1, interval: 1, weekStart: time.Monday, dtstart: dtstart}
+	invalid := func(part string, reason string) error {
+		return errors.Errorf("Invalid %s part '%s': %s", name, part, reason)
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, invalid(part, "not like NAME=VALUE")
+		}
+		var err error
+		switch key, v := strings.ToUpper(kv[0]), strings.ToUpper(kv[1]); key {
+		case "FREQ":
+			for freq, f := range ruleFrequencies {
+				if v == f {
+					r.freq = freq
+				}
+			}
+			if r.freq < 0 {
+				return nil, invalid(part, "the frequency must be one of "+strings.Join(ruleFrequencies, ", "))
+			}
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(v); err != nil || r.interval < 1 {
+				return nil, invalid(part, "the interval must be a positive number")
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(v); err != nil || r.count < 1 {
+				return nil, invalid(part, "the count must be a positive number")
+			}
+		case "UNTIL":
+			until, isDate, err := parseDateTime(v, dtstart.Location())
+			if err != nil {
+				return nil, invalid(part, "the end must be a date or a date-time")
+			}
+			if isDate {
+				// a date includes the whole day
+				until = until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			r.until = until
+		case "BYSECOND":
+			r.bySecond, err = parseRuleInts(v, 0, 59, false)
+		case "BYMINUTE":
+			r.byMinute, err = parseRuleInts(v, 0, 59, false)
+		case "BYHOUR":
+			r.byHour, err = parseRuleInts(v, 0, 23, false)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRuleInts(v, 1, 31, true)
+		case "BYYEARDAY":
+			r.byYearDay, err = parseRuleInts(v, 1, 366, true)
+		case "BYMONTH":
+			r.byMonth, err = parseRuleInts(v, 1, 12, false)
+		case "BYSETPOS":
+			r.bySetPos, err = parseRuleInts(v, 1, 366, true)
+		case "BYDAY":
+			r.byDay, err = parseRuleWeekdays(v)
+		case "WKST":
+			weekday, ok := ruleWeekdays[v]
+			if !ok {
+				return nil, invalid(part, "the week start must be one of MO, TU, WE, TH, FR, SA, SU")
+			}
+			r.weekStart = weekday
+		default:
+			return nil, invalid(part, "unsupported part")
+		}
+		if err != nil {
+			return nil, invalid(part, err.Error())
+		}
+	}
+
+	if r.freq < 0 {
+		return nil, errors.Errorf("Invalid %s '%s': FREQ is required", name, value)
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return nil, errors.Errorf("Invalid %s '%s': COUNT and UNTIL can't be both set", name, value)
+	}
+	for _, wd := range r.byDay {
+		if wd.n != 0 && r.freq != monthly && r.freq != yearly {
+			return nil, errors.Errorf("Invalid %s '%s': BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY", name, value)
+		}
+	}
+
+	// without days, the days are the ones of DTSTART
+	if len(r.byDay)+len(r.byMonthDay)+len(r.byYearDay) == 0 {
+		switch r.freq {
+		case yearly:
+			if len(r.byMonth) == 0 {
+				r.byMonth = []int{int(dtstart.Month())}
+			}
+			r.byMonthDay = []int{dtstart.Day()}
+		case monthly:
+			r.byMonthDay = []int{dtstart.Day()}
+		case weekly:
+			r.byDay = []ruleWeekday{{weekday: dtstart.Weekday()}}
+		}
+	}
+
+	r.base = r.periodStart(dtstart)
+	return r, nil
+}
+
+// parseRuleInts parses a comma separated list of numbers within min and max,
+// and within -max and -min if signed
+func parseRuleInts(value string, min, max int, signed bool) ([]int, error) {
+	var values []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Errorf("'%s' is not a number", v)
+		}
+		abs := n
+		if signed && n < 0 {
+			abs = -n
+		}
+		if abs < min || abs > max {
+			return nil, errors.Errorf("%d is out of the range %d-%d", n, min, max)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseRuleWeekdays parses BYDAY values like "MO,TU", "-1FR" or "2TU"
+func parseRuleWeekdays(value string) ([]ruleWeekday, error) {
+	var weekdays []ruleWeekday
+	for _, v := range strings.Split(value, ",") {
+		if len(v) < 2 {
+			return nil, errors.Errorf("'%s' is not a weekday", v)
+		}
+		weekday, ok := ruleWeekdays[v[len(v)-2:]]
+		if !ok {
+			return nil, errors.Errorf("'%s' is not a weekday", v)
+		}
+		n := 0
+		if ordinal := v[:len(v)-2]; ordinal != "" {
+			var err error
+			n, err = strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, errors.Errorf("the ordinal of '%s' must be between 1 and 53, or -53 and -1", v)
+			}
+		}
+		weekdays = append(weekdays, ruleWeekday{weekday: weekday, n: n})
+	}
+	return weekdays, nil
+}
+
+// Next returns the first occurrence of the recurrence set after t, in the location of t
+func (s *ruleSet) Next(t time.Time) time.Time {
+	for i := 0; i < maxCompositeSteps; i++ {
+		next := time.Time{}
+		for _, rule := range s.rrules {
+			if occurrence := rule.next(t); !occurrence.IsZero() && (next.IsZero() || occurrence.Before(next)) {
+				next = occurrence
+			}
+		}
+		for _, rdate := range s.rdates {
+			if rdate.After(t) {
+				if next.IsZero() || rdate.Before(next) {
+					next = rdate
+				}
+				break
+			}
+		}
+
+		if next.IsZero() || !s.excluded(next) {
+			if next.IsZero() {
+				return next
+			}
+			return next.In(t.Location())
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// excluded reports if an occurrence is excluded by an EXDATE or an EXRULE
+func (s *ruleSet) excluded(occurrence time.Time) bool {
+	if s.exdays[occurrence.In(s.dtstart.Location()).Format(icalDate)] {
+		return true
+	}
+	for _, exdate := range s.exdates {
+		if exdate.Equal(occurrence) {
+			return true
+		}
+	}
+	for _, rule := range s.exrules {
+		if rule.next(occurrence.Add(-time.Second)).Equal(occurrence) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the first occurrence of the rule after t
+func (r *recurrenceRule) next(t time.Time) time.Time {
+	k, n := 0, 0
+	if r.count == 0 {
+		// the occurrences don't need to be counted from the start
+		k = r.periodBefore(t)
+	}
+
+	for steps := 0; steps < maxRulePeriods; steps++ {
+		occurrences, nextPeriod := r.period(k)
+		for _, occurrence := range occurrences {
+			if occurrence.Before(r.dtstart) {
+				continue
+			}
+			if !r.until.IsZero() && occurrence.After(r.until) {
+				return time.Time{}
+			}
+			n++
+			if r.count > 0 && n > r.count {
+				return time.Time{}
+			}
+			if occurrence.After(t) {
+				return occurrence
+			}
+		}
+		if !r.until.IsZero() && r.periodAt(nextPeriod).After(r.until) {
+			return time.Time{}
+		}
+		k = nextPeriod
+	}
+	return time.Time{}
+}
+
+// periodStart returns the start of the period holding t
+func (r *recurrenceRule) periodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch r.freq {
+	case secondly:
+		return t.Truncate(time.Second)
+	case minutely:
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+	case hourly:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+	case daily:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case weekly:
+		return time.Date(y, m, d-(int(t.Weekday())-int(r.weekStart)+7)%7, 0, 0, 0, 0, loc)
+	case monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+}
+
+// periodAt returns the start of the k-th period
+func (r *recurrenceRule) periodAt(k int) time.Time {
+	steps := k * r.interval
+	switch r.freq {
+	case secondly:
+		return r.base.Add(time.Duration(steps) * time.Second)
+	case minutely:
+		return r.base.Add(time.Duration(steps) * time.Minute)
+	case hourly:
+		return r.base.Add(time.Duration(steps) * time.Hour)
+	case daily:
+		return r.base.AddDate(0, 0, steps)
+	case weekly:
+		return r.base.AddDate(0, 0, 7*steps)
+	case monthly:
+		return r.base.AddDate(0, steps, 0)
+	}
+	return r.base.AddDate(steps, 0, 0)
+}
+
+// periodBefore returns a period starting before t, not after the first one
+func (r *recurrenceRule) periodBefore(t time.Time) int {
+	t = t.In(r.base.Location())
+	var k int
+	switch r.freq {
+	case secondly, minutely, hourly:
+		unit := []time.Duration{time.Second, time.Minute, time.Hour}[r.freq]
+		k = int(t.Sub(r.base) / (unit * time.Duration(r.interval)))
+	case daily, weekly:
+		days := int(civilDay(t).Sub(civilDay(r.base)) / (24 * time.Hour))
+		if r.freq == weekly {
+			days /= 7
+		}
+		k = days / r.interval
+	case monthly:
+		k = ((t.Year()-r.base.Year())*12 + int(t.Month()-r.base.Month())) / r.interval
+	case yearly:
+		k = (t.Year() - r.base.Year()) / r.interval
+	}
+	if k--; k < 0 {
+		return 0
+	}
+	return k
+}
+
+// civilDay returns the date of t at midnight UTC, to count days across daylight saving time changes
+func civilDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// wallClock returns the time at a wall clock in loc, a wall clock in a daylight saving time gap
+// is read with the offset before the gap, as RFC 5545 wants: 02:30 is 03:30 on a 02:00 to 03:00 gap
+func wallClock(year int, month time.Month, day, hour, minute, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, sec, 0, loc)
+	if t.Day() == day && t.Hour() == hour && t.Minute() == minute {
+		return t
+	}
+	// the offset before the gap, the gaps being shorter than 3h
+	_, offset := t.Add(-3 * time.Hour).Zone()
+	utc := time.Date(year, month, day, hour, minute, sec, 0, time.UTC)
+	return utc.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// period returns the sorted occurrences of the k-th period, and the next period to visit
+func (r *recurrenceRule) period(k int) ([]time.Time, int) {
+	start := r.periodAt(k)
+	loc := start.Location()
+
+	var days []time.Time
+	switch r.freq {
+	case secondly, minutely, hourly:
+		if !r.dayMatches(start) {
+			// skip to the first period of the next day
+			next := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc)
+			skip := r.periodBefore(next)
+			if skip <= k {
+				skip = k + 1
+			}
+			for r.periodAt(skip).Before(next) {
+				skip++
+			}
+			return nil, skip
+		}
+		days = []time.Time{start}
+	case daily:
+		days = []time.Time{start}
+	case weekly:
+		days = r.daysFrom(start, 7)
+	case monthly:
+		days = r.daysFrom(start, daysIn(start))
+	case yearly:
+		days = r.daysFrom(start, daysInYear(start))
+	}
+
+	var occurrences []time.Time
+	for _, day := range days {
+		if !r.dayMatches(day) {
+			continue
+		}
+		hours, minutes, seconds := r.timesOf(start)
+		for _, h := range hours {
+			for _, m := range minutes {
+				for _, s := range seconds {
+					occurrences = append(occurrences, wallClock(day.Year(), day.Month(), day.Day(), h, m, s, loc))
+				}
+			}
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+	return r.setPositions(occurrences), k + 1
+}
+
+// daysFrom returns n consecutive days from start
+func (r *recurrenceRule) daysFrom(start time.Time, n int) []time.Time {
+	days := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		days = append(days, start.AddDate(0, 0, i))
+	}
+	return days
+}
+
+// daysInYear returns the number of days in the year of t
+func daysInYear(t time.Time) int {
+	return time.Date(t.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+// timesOf returns the hours, minutes and seconds of the occurrences of a period:
+// the ones of the period itself below the frequency, the BY ones or the ones of DTSTART above it
+func (r *recurrenceRule) timesOf(start time.Time) ([]int, []int, []int) {
+	field := func(freq int, value int, by []int, dtstart int) []int {
+		switch {
+		case r.freq <= freq:
+			if len(by) == 0 || containsInt(by, value) {
+				return []int{value}
+			}
+			return nil
+		case len(by) > 0:
+			return by
+		}
+		return []int{dtstart}
+	}
+	return field(hourly, start.Hour(), r.byHour, r.dtstart.Hour()),
+		field(minutely, start.Minute(), r.byMinute, r.dtstart.Minute()),
+		field(secondly, start.Second(), r.bySecond, r.dtstart.Second())
+}
+
+// dayMatches reports if a day satisfies the BYMONTH, BYMONTHDAY, BYYEARDAY and BYDAY parts
+func (r *recurrenceRule) dayMatches(day time.Time) bool {
+	if len(r.byMonth) > 0 && !containsInt(r.byMonth, int(day.Month())) {
+		return false
+	}
+
+	monthDays, yearDays := daysIn(day), daysInYear(day)
+	if len(r.byMonthDay) > 0 && !containsInt(r.byMonthDay, day.Day()) && !containsInt(r.byMonthDay, day.Day()-monthDays-1) {
+		return false
+	}
+	if len(r.byYearDay) > 0 && !containsInt(r.byYearDay, day.YearDay()) && !containsInt(r.byYearDay, day.YearDay()-yearDays-1) {
+		return false
+	}
+	if len(r.byDay) == 0 {
+		return true
+	}
+
+	for _, wd := range r.byDay {
+		if wd.weekday != day.Weekday() {
+			continue
+		}
+		// the ordinals are within the month, or the year for yearly rules without months
+		position, last := day.Day(), monthDays
+		if r.freq == yearly && len(r.byMonth) == 0 {
+			position, last = day.YearDay(), yearDays
+		}
+		switch {
+		case wd.n == 0:
+			return true
+		case wd.n > 0 && (position-1)/7+1 == wd.n:
+			return true
+		case wd.n < 0 && (last-position)/7+1 == -wd.n:
+			return true
+		}
+	}
+	return false
+}
+
+// setPositions keeps the occurrences of a period at the BYSETPOS positions, if any
+func (r *recurrenceRule) setPositions(occurrences []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return occurrences
+	}
+	var kept []time.Time
+	for i, occurrence := range occurrences {
+		if containsInt(r.bySetPos, i+1) || containsInt(r.bySetPos, i-len(occurrences)) {
+			kept = append(kept, occurrence)
+		}
+	}
+	return kept
+}
+
+// containsInt reports if values contains v
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package grontab
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleSet(t *testing.T) {
+	tests := []struct {
+		expr     string
+		from     string
+		expected []string
+	}{
+		{
+			"DTSTART;TZID=Europe/Rome:20260106T090000 RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20260205T235959Z",
+			"2026-01-01T00:00:00+01:00",
+			[]string{"2026-01-06T09:00:00+01:00", "2026-01-08T09:00:00+01:00", "2026-01-20T09:00:00+01:00",
+				"2026-01-22T09:00:00+01:00", "2026-02-03T09:00:00+01:00", "2026-02-05T09:00:00+01:00"},
+		},
+		{
+			// the last business day of the month, across the change to summer time
+			"DTSTART;TZID=Europe/Rome:20260101T180000\nRRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			"2026-01-01T00:00:00+01:00",
+			[]string{"2026-01-30T18:00:00+01:00", "2026-02-27T18:00:00+01:00", "2026-03-31T18:00:00+02:00",
+				"2026-04-30T18:00:00+02:00", "2026-05-29T18:00:00+02:00"},
+		},
+		{
+			"DTSTART:20260101T000000Z RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH RDATE:20270101T120000Z",
+			"2026-01-01T00:00:00Z",
+			[]string{"2026-11-26T00:00:00Z", "2027-01-01T12:00:00Z", "2027-11-25T00:00:00Z"},
+		},
+		{
+			"DTSTART:20260101T000000Z RRULE:FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR;COUNT=2",
+			"2026-01-01T00:00:00Z",
+			[]string{"2026-02-13T00:00:00Z", "2026-03-13T00:00:00Z"},
+		},
+		{
+			"DTSTART:20260101T083000Z RRULE:FREQ=MONTHLY;BYMONTHDAY=-1",
+			"2026-01-01T00:00:00Z",
+			[]string{"2026-01-31T08:30:00Z", "2026-02-28T08:30:00Z", "2026-03-31T08:30:00Z"},
+		},
+		{
+			"DTSTART:20260101T000000Z RRULE:FREQ=HOURLY;INTERVAL=6;BYHOUR=6,12",
+			"2026-06-01T00:00:00Z",
+			[]string{"2026-06-01T06:00:00Z", "2026-06-01T12:00:00Z", "2026-06-02T06:00:00Z"},
+		},
+		{
+			"DTSTART;TZID=Europe/Rome:20261221T090000 RRULE:FREQ=DAILY EXRULE:FREQ=WEEKLY;BYDAY=SA,SU " +
+				"EXDATE;VALUE=DATE:20261225 EXDATE;TZID=Europe/Rome:20261228T090000",
+			"2026-12-21T00:00:00+01:00",
+			[]string{"2026-12-21T09:00:00+01:00", "2026-12-22T09:00:00+01:00", "2026-12-23T09:00:00+01:00",
+				"2026-12-24T09:00:00+01:00", "2026-12-29T09:00:00+01:00"},
+		},
+	}
+
+	for _, test := range tests {
+		from, _ := time.Parse(time.RFC3339, test.from)
+		times, err := PreviewSchedule(test.expr, from, len(test.expected)+1, "Europe/Rome")
+		if err != nil {
+			t.Errorf("PreviewSchedule('%s') returned %v", test.expr, err)
+			continue
+		}
+		if len(times) < len(test.expected) {
+			t.Errorf("expected '%s' to activate at %v, got %v", test.expr, test.expected, times)
+			continue
+		}
+		for i, expected := range test.expected {
+			at, _ := time.Parse(time.RFC3339, expected)
+			if !times[i].Equal(at) {
+				t.Errorf("expected activation %d of '%s' at %s, got %s", i, test.expr, at, times[i])
+			}
+		}
+	}
+
+	// the rules with a count or an end stop
+	times, _ := PreviewSchedule(tests[0].expr, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), 10, "UTC")
+	if len(times) != 6 {
+		t.Errorf("expected '%s' to stop after 6 activations, got %v", tests[0].expr, times)
+	}
+}
+
+func TestRuleSetDSTGap(t *testing.T) {
+	// 02:30 doesn't exist on the change to summer time, it is read with the offset before the gap
+	expr := "DTSTART;TZID=America/New_York:20260301T023000 RRULE:FREQ=DAILY"
+	times, err := PreviewSchedule(expr, time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC), 3, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"2026-03-07T02:30:00-05:00", "2026-03-08T03:30:00-04:00", "2026-03-09T02:30:00-04:00"}
+	if len(times) != len(expected) {
+		t.Fatalf("expected '%s' to activate at %v, got %v", expr, expected, times)
+	}
+	for i := range expected {
+		at, _ := time.Parse(time.RFC3339, expected[i])
+		if !times[i].Equal(at) {
+			t.Errorf("expected activation %d of '%s' at %s, got %s", i, expr, at, times[i])
+		}
+	}
+
+	// the dates of RDATE take the time of DTSTART, moved out of the gap the same way
+	expr = "DTSTART;TZID=America/New_York:20260301T023000 RDATE;VALUE=DATE:20260308"
+	times, err = PreviewSchedule(expr, time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC), 1, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at, _ := time.Parse(time.RFC3339, expected[1]); len(times) != 1 || !times[0].Equal(at) {
+		t.Errorf("expected '%s' to activate at %s, got %v", expr, at, times)
+	}
+}
+
+func TestRuleSetErrors(t *testing.T) {
+	for _, expr := range []string{
+		"RRULE:FREQ=DAILY",
+		"DTSTART:20260101T000000Z",
+		"DTSTART:20260101T000000Z RRULE:FREQ=FORTNIGHTLY",
+		"DTSTART:20260101T000000Z RRULE:INTERVAL=2",
+		"DTSTART:20260101T000000Z RRULE:FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"DTSTART:20260101T000000Z RRULE:FREQ=WEEKLY;BYDAY=2MO",
+		"DTSTART:20260101T000000Z RRULE:FREQ=DAILY;BYHOUR=24",
+		"DTSTART:20260101T000000Z RRULE:FREQ=DAILY;BYWEEKNO=1",
+		"DTSTART;TZID=Mars/Olympus:20260101T000000 RRULE:FREQ=DAILY",
+		"DTSTART:20260101T000000Z RRULE:FREQ=DAILY VEVENT:X",
+	} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("expected parseSchedule('%s') to fail", expr)
+		}
+	}
+}
+
+func TestRuleSetExplain(t *testing.T) {
+	description, err := Explain("DTSTART;TZID=Europe/Rome:20260101T180000 RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "every month on Monday, Tuesday, Wednesday, Thursday and Friday at 18:00:00 only the last of each month, " +
+		"starting 2026-01-01 18:00:00 Europe/Rome"
+	if description != expected {
+		t.Errorf("expected '%s', got '%s'", expected, description)
+	}
+}
+
+func TestRuleSetJobs(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	schedule := "DTSTART;TZID=Europe/Rome:20260101T090000\nRRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR"
+	_, err := Add(schedule, Job{ID: "standup", Task: "echo 'standup'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var job Job
+	err = store.View(func(tx Tx) error {
+		var err error
+		job, err = tx.GetJob("standup")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRuleSet(job.Schedule) || !strings.Contains(job.Schedule, "BYDAY=MO,WE,FR") {
+		t.Errorf("expected the recurrence set to be persisted with the job, got '%s'", job.Schedule)
+	}
+
+	times, err := NextRuns("standup", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range times {
+		at = at.In(time.UTC)
+		if weekday := at.Weekday(); weekday != time.Monday && weekday != time.Wednesday && weekday != time.Friday {
+			t.Errorf("expected the job to run on Monday, Wednesday and Friday, got %s", at)
+		}
+	}
+	if len(times) != 3 {
+		t.Errorf("expected 3 next runs, got %v", times)
+	}
+}
@@ -0,0 +1,112 @@
+package grontab
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// the maximum number of activation times returned by NextRuns and PreviewSchedule
+const maxPreviewRuns = 1000
+
+// NextRuns returns the next n activation times of a job, with its H tokens and @random times resolved,
+// within its validity window and runs left, and the days excluded by its calendars skipped
+func NextRuns(id string, n int) ([]time.Time, error) {
+	return nextRuns(id, n)
+}
+
+// PreviewSchedule returns the first n activation times of a schedule expression after from,
+// in the tz time zone (an IANA name like "Europe/Rome", the local one if empty)
+func PreviewSchedule(expr string, from time.Time, n int, tz string) ([]time.Time, error) {
+	return previewSchedule(expr, from, n, tz)
+}
+
+// parseSchedule parses a schedule expression, a descriptor, a composite one, an iCalendar recurrence set
+// or a cron one with the quartz-style extensions, into a cron schedule,
+// the H tokens not resolved for a job are resolved as for an empty job ID
+func parseSchedule(expr string) (cron.Schedule, error) {
+	expr, err := resolveSchedule(expr, "")
+	if err != nil {
+		return nil, err
+	}
+	if isComposite(expr) {
+		return parseComposite(expr)
+	}
+	if isRuleSet(expr) {
+		return parseRuleSet(expr)
+	}
+	if isAt(expr) {
+		return parseAt(expr)
+	}
+	if isLifecycle(expr) {
+		return parseLifecycle(expr)
+	}
+	if isDelay(expr) {
+		return parseDelay(expr)
+	}
+	if strings.HasPrefix(expr, "@") {
+		return cron.Parse(expr)
+	}
+	return parseSpec(expr)
+}
+
+func nextRuns(jid string, n int) ([]time.Time, error) {
+	var schedule cron.Schedule
+	err := store.View(func(tx Tx) error {
+		job, err := tx.GetJob(jid)
+		if err != nil {
+			return errors.Wrap(err, "Error Getting Job "+jid)
+		}
+		schedule, err = jobRunSchedule(tx, job)
+		if err != nil {
+			return errors.Wrap(err, "Error Parsing schedule of Job "+jid)
+		}
+		// the runs left to the jobs with MaxRuns
+		if remaining := job.remainingRuns(); remaining >= 0 && remaining < n {
+			n = remaining
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activations(schedule, time.Now().In(c.Location()), n), nil
+}
+
+func previewSchedule(expr string, from time.Time, n int, tz string) ([]time.Time, error) {
+	loc := time.Local
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error Loading time zone "+tz)
+		}
+	}
+
+	schedule, err := parseSchedule(expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Parsing schedule")
+	}
+	return activations(schedule, from.In(loc), n), nil
+}
+
+// activations returns up to n activation times of a schedule after from,
+// fewer if the schedule can't be satisfied anymore
+func activations(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	if n > maxPreviewRuns {
+		n = maxPreviewRuns
+	}
+
+	var times []time.Time
+	t := from
+	for len(times) < n {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
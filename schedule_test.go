@@ -0,0 +1,77 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPreviewSchedule(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	times, err := PreviewSchedule("0 30 6 * * 1-5", from, 3, "Europe/Rome")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"2026-01-01T06:30:00+01:00",
+		"2026-01-02T06:30:00+01:00",
+		"2026-01-05T06:30:00+01:00",
+	}
+	if len(times) != len(expected) {
+		t.Fatalf("expected %d activations, got %v", len(expected), times)
+	}
+	for i := range times {
+		if times[i].Format(time.RFC3339) != expected[i] {
+			t.Errorf("expected activation %d to be %s, got %s", i, expected[i], times[i].Format(time.RFC3339))
+		}
+	}
+
+	_, err = PreviewSchedule("not a schedule", from, 3, "")
+	if err == nil {
+		t.Errorf("expected PreviewSchedule() to refuse an invalid schedule")
+	}
+
+	_, err = PreviewSchedule("0 30 6 * * *", from, 3, "Not/AZone")
+	if err == nil {
+		t.Errorf("expected PreviewSchedule() to refuse an invalid time zone")
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	id, err := Add("*/10 * * * * *", Job{Task: "echo 'ciaone'", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times, err := NextRuns(id, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 3 || times[1].Sub(times[0]) != 10*time.Second || times[0].Second()%10 != 0 {
+		t.Errorf("expected NextRuns() to return activations every 10 seconds, got %v", times)
+	}
+
+	jobs, err := ListJobs(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a 10 seconds boundary may have passed in between
+	if len(jobs) != 1 || !(jobs[0].Next.Equal(times[0]) || jobs[0].Next.Equal(times[1])) {
+		t.Errorf("expected ListJobs() to fill in the next activation %s, got %v", times[0], jobs)
+	}
+
+	_, err = NextRuns("dasdasd", 3)
+	if err == nil {
+		t.Errorf("expected NextRuns() to raise an error for a non existing job")
+	}
+}
@@ -0,0 +1,124 @@
+package grontab
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNewerSchema is returned by Init when the storage was written by a newer grontab version
+var ErrNewerSchema = errors.New("storage written by a newer grontab version")
+
+// migration upgrades the storage from the previous schema version to version
+type migration struct {
+	version     int
+	description string
+	migrate     func(tx Tx) error
+}
+
+// migrations is the registry of the schema migrations, in ascending version order,
+// version 1 is the schema written before the schema was versioned
+var migrations = []migration{
+	{
+		version:     2,
+		description: "one record per job instead of jobgroups keyed by schedule",
+		migrate: func(tx Tx) error {
+			// only the bolt store ever kept jobgroups keyed by schedule
+			if btx, ok := tx.(*boltTx); ok {
+				return btx.migrateLegacyGroups()
+			}
+			return nil
+		},
+	},
+}
+
+// schemaVersion is the schema version written by this version of grontab
+var schemaVersion = migrations[len(migrations)-1].version
+
+// backupStore is implemented by the stores able to backup their content before a migration
+type backupStore interface {
+	backup(version int) (string, error)
+}
+
+// migrateSchema runs, in a single transaction, the migrations needed
+// to bring the storage up to the current schema version
+func migrateSchema() error {
+	version, err := readSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == schemaVersion {
+		return nil
+	}
+
+	// an empty store has nothing to migrate
+	if version == 0 {
+		return store.Update(func(tx Tx) error {
+			return tx.SetSchemaVersion(schemaVersion)
+		})
+	}
+
+	// keep a copy of the storage as it was before migrating
+	if bs, ok := store.(backupStore); ok {
+		path, err := bs.backup(version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Backed up the storage at schema version %d to %s", version, path)
+	}
+
+	return store.Update(func(tx Tx) error {
+		for _, m := range migrations {
+			if m.version <= version {
+				continue
+			}
+			log.Printf("Migrating the storage to schema version %d: %s", m.version, m.description)
+			err := m.migrate(tx)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error Migrating to schema version %d", m.version))
+			}
+			err = tx.SetSchemaVersion(m.version)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkSchema checks that the storage is at the current schema version, for reading it without migrating it
+func checkSchema() error {
+	version, err := readSchemaVersion()
+	if err != nil {
+		return err
+	}
+	// an empty store has no schema yet
+	if version != 0 && version < schemaVersion {
+		return errors.Errorf("schema version %d, Init migrates it to version %d when not read only", version, schemaVersion)
+	}
+	return nil
+}
+
+// readSchemaVersion returns the schema version of the storage, refusing the ones written by a newer grontab
+func readSchemaVersion() (int, error) {
+	var version int
+	err := store.View(func(tx Tx) error {
+		var err error
+		version, err = tx.SchemaVersion()
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Error Reading schema version")
+	}
+	if version > schemaVersion {
+		return 0, errors.Wrap(ErrNewerSchema, fmt.Sprintf("schema version %d, this grontab supports up to version %d", version, schemaVersion))
+	}
+	return version, nil
+}
+
+// backupPath returns the path of the backup of the file at path, taken at schema version
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.%s.bak", path, version, time.Now().Format("20060102150405"))
+}
@@ -0,0 +1,45 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestInitSetsSchemaVersion(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	defer Stop()
+
+	var version int
+	store.View(func(tx Tx) error {
+		var err error
+		version, err = tx.SchemaVersion()
+		return err
+	})
+	if version != schemaVersion {
+		t.Errorf("expected Init() to record schema version %d, got %d", schemaVersion, version)
+	}
+}
+
+func TestInitRefusesNewerSchema(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	s := NewMemoryStore()
+	s.Update(func(tx Tx) error {
+		return tx.SetSchemaVersion(schemaVersion + 1)
+	})
+
+	err := Init(Config{TurnOffLogs: true, HideBanner: true, Store: s})
+	if errors.Cause(err) != ErrNewerSchema {
+		t.Errorf("expected Init() to refuse a storage written by a newer version, got %v", err)
+	}
+}
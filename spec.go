@@ -0,0 +1,467 @@
+package grontab
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// FieldError defines an invalid field of a schedule expression
+type FieldError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return "Invalid " + e.Field + " field '" + e.Value + "': " + e.Reason
+}
+
+// fieldBounds defines the values accepted by a field of a schedule expression
+type fieldBounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+// the bounds of the fields of a schedule expression, in order
+var (
+	secondBounds  = fieldBounds{name: "second", min: 0, max: 59}
+	minuteBounds  = fieldBounds{name: "minute", min: 0, max: 59}
+	hourBounds    = fieldBounds{name: "hour", min: 0, max: 23}
+	domBounds     = fieldBounds{name: "day-of-month", min: 1, max: 31}
+	monthBounds   = fieldBounds{name: "month", min: 1, max: 12, names: monthValues}
+	weekdayBounds = fieldBounds{name: "day-of-week", min: 0, max: 6, names: weekdayValues}
+	yearBounds    = fieldBounds{name: "year", min: 1970, max: 2099}
+)
+
+// nthWeekday is a "n#k" day of week value, the k-th weekday n of the month
+type nthWeekday struct {
+	weekday, nth int
+}
+
+// extendedSchedule is a cron schedule with the quartz-style extensions:
+// "L", "L-n", "LW" and "nW" in the day of month field, "nL" and "n#k" in the day of week field,
+// and an optional year field
+type extendedSchedule struct {
+	cron.SpecSchedule
+
+	// the last day of the month minus each offset, from "L" and "L-n"
+	lastDayOffsets []int
+	// the last weekday (monday to friday) of the month, from "LW"
+	lastWeekday bool
+	// the weekdays nearest to each day of the month, from "nW"
+	nearestWeekdays []int
+	// the last of each weekday in the month, from "nL"
+	lastWeekdays []int
+	// the k-th weekdays of the month, from "n#k"
+	nthWeekdays []nthWeekday
+	// the years, nil matches every year
+	years    map[int]bool
+	lastYear int
+}
+
+// parseSpec parses a cron expression of 5 to 7 fields:
+// second minute hour day-of-month month [day-of-week [year]],
+// it returns a plain cron schedule when no extension is used
+func parseSpec(expr string) (cron.Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 5 || len(fields) > 7 {
+		return nil, errors.Errorf("Expected 5 to 7 fields, found %d: %s", len(fields), expr)
+	}
+	fields = expandScheduleFields(fields)
+
+	s := &extendedSchedule{}
+	plainFields := []struct {
+		value  string
+		bounds fieldBounds
+		bits   *uint64
+	}{
+		{fields[0], secondBounds, &s.Second},
+		{fields[1], minuteBounds, &s.Minute},
+		{fields[2], hourBounds, &s.Hour},
+		{fields[4], monthBounds, &s.Month},
+	}
+	var err error
+	for _, f := range plainFields {
+		if *f.bits, err = parseBits(f.value, f.bounds); err != nil {
+			return nil, &FieldError{Field: f.bounds.name, Value: f.value, Reason: err.Error()}
+		}
+	}
+	if err = s.parseDom(fields[3]); err != nil {
+		return nil, &FieldError{Field: domBounds.name, Value: fields[3], Reason: err.Error()}
+	}
+	if err = s.parseDow(fields[5]); err != nil {
+		return nil, &FieldError{Field: weekdayBounds.name, Value: fields[5], Reason: err.Error()}
+	}
+	if len(fields) == 7 {
+		if err = s.parseYears(fields[6]); err != nil {
+			return nil, &FieldError{Field: yearBounds.name, Value: fields[6], Reason: err.Error()}
+		}
+	}
+
+	if !s.extended() {
+		return &s.SpecSchedule, nil
+	}
+	return s, nil
+}
+
+// extended reports if the schedule uses any of the extensions
+func (s *extendedSchedule) extended() bool {
+	return len(s.lastDayOffsets) > 0 || s.lastWeekday || len(s.nearestWeekdays) > 0 ||
+		len(s.lastWeekdays) > 0 || len(s.nthWeekdays) > 0 || s.years != nil
+}
+
+// parseDom parses the day of month field, with "L", "L-n", "LW" and "nW"
+func (s *extendedSchedule) parseDom(field string) error {
+	var ranges []string
+	for _, part := range strings.Split(field, ",") {
+		switch {
+		case part == "L":
+			s.lastDayOffsets = append(s.lastDayOffsets, 0)
+		case strings.HasPrefix(part, "L-"):
+			offset, err := strconv.Atoi(part[2:])
+			if err != nil || offset < 1 || offset > 30 {
+				return errors.Errorf("the offset of '%s' must be between 1 and 30", part)
+			}
+			s.lastDayOffsets = append(s.lastDayOffsets, offset)
+		case part == "LW":
+			s.lastWeekday = true
+		case strings.HasSuffix(part, "W"):
+			day, err := strconv.Atoi(strings.TrimSuffix(part, "W"))
+			if err != nil || day < domBounds.min || day > domBounds.max {
+				return errors.Errorf("the day of '%s' must be between %d and %d", part, domBounds.min, domBounds.max)
+			}
+			s.nearestWeekdays = append(s.nearestWeekdays, day)
+		default:
+			ranges = append(ranges, part)
+		}
+	}
+	return s.parseRanges(ranges, domBounds, &s.Dom)
+}
+
+// parseDow parses the day of week field, with "nL" and "n#k"
+func (s *extendedSchedule) parseDow(field string) error {
+	var ranges []string
+	for _, part := range strings.Split(field, ",") {
+		switch {
+		case strings.Contains(part, "#"):
+			weekdayAndNth := strings.SplitN(part, "#", 2)
+			weekday, err := parseValue(weekdayAndNth[0], weekdayBounds)
+			if err != nil {
+				return err
+			}
+			nth, err := strconv.Atoi(weekdayAndNth[1])
+			if err != nil || nth < 1 || nth > 5 {
+				return errors.Errorf("the week of '%s' must be between 1 and 5", part)
+			}
+			s.nthWeekdays = append(s.nthWeekdays, nthWeekday{weekday: weekday, nth: nth})
+		case len(part) > 1 && strings.HasSuffix(part, "L"):
+			weekday, err := parseValue(strings.TrimSuffix(part, "L"), weekdayBounds)
+			if err != nil {
+				return err
+			}
+			s.lastWeekdays = append(s.lastWeekdays, weekday)
+		default:
+			ranges = append(ranges, part)
+		}
+	}
+	return s.parseRanges(ranges, weekdayBounds, &s.Dow)
+}
+
+// parseRanges parses the plain ranges of a day field, if any
+func (s *extendedSchedule) parseRanges(ranges []string, bounds fieldBounds, bits *uint64) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+	var err error
+	*bits, err = parseBits(strings.Join(ranges, ","), bounds)
+	return err
+}
+
+// parseYears parses the year field
+func (s *extendedSchedule) parseYears(field string) error {
+	years, star, err := parseValues(field, yearBounds)
+	if err != nil || star {
+		return err
+	}
+	s.years = make(map[int]bool)
+	for _, year := range years {
+		s.years[year] = true
+		if year > s.lastYear {
+			s.lastYear = year
+		}
+	}
+	return nil
+}
+
+// the top bit set by the cron parser when a field is a star
+const starBit = 1 << 63
+
+// parseBits parses a field into the bit set used by the cron engine,
+// with the top bit set when the field has a star
+func parseBits(field string, bounds fieldBounds) (uint64, error) {
+	values, star, err := parseValues(field, bounds)
+	if err != nil {
+		return 0, err
+	}
+	var bits uint64
+	for _, v := range values {
+		bits |= 1 << uint(v)
+	}
+	if star {
+		bits |= starBit
+	}
+	return bits, nil
+}
+
+// parseValues parses a comma separated list of ranges like "*", "1-5", "*/10", "jan-mar" or "3/2",
+// it reports if any of the ranges is a star
+func parseValues(field string, bounds fieldBounds) ([]int, bool, error) {
+	var values []int
+	star := false
+	for _, r := range strings.Split(field, ",") {
+		rangeAndStep := strings.Split(r, "/")
+		if len(rangeAndStep) > 2 {
+			return nil, false, errors.Errorf("too many slashes in '%s'", r)
+		}
+		lowAndHigh := strings.Split(rangeAndStep[0], "-")
+		if len(lowAndHigh) > 2 {
+			return nil, false, errors.Errorf("too many hyphens in '%s'", r)
+		}
+
+		var low, high int
+		var err error
+		if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+			low, high, star = bounds.min, bounds.max, true
+		} else {
+			if low, err = parseValue(lowAndHigh[0], bounds); err != nil {
+				return nil, false, err
+			}
+			high = low
+			if len(lowAndHigh) == 2 {
+				if high, err = parseValue(lowAndHigh[1], bounds); err != nil {
+					return nil, false, err
+				}
+			}
+		}
+
+		step := 1
+		if len(rangeAndStep) == 2 {
+			step, err = strconv.Atoi(rangeAndStep[1])
+			if err != nil || step < 1 {
+				return nil, false, errors.Errorf("the step of '%s' must be a positive number", r)
+			}
+			// "n/step" means "n-max/step"
+			if len(lowAndHigh) == 1 {
+				high = bounds.max
+			}
+		}
+		if low > high {
+			return nil, false, errors.Errorf("the range '%s' begins after its end", r)
+		}
+
+		for v := low; v <= high; v += step {
+			values = append(values, v)
+		}
+	}
+	return values, star, nil
+}
+
+// parseValue parses a single value of a field, a number or a name
+func parseValue(value string, bounds fieldBounds) (int, error) {
+	v, ok := bounds.names[strings.ToLower(value)]
+	if !ok {
+		var err error
+		v, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, errors.Errorf("'%s' is not a number", value)
+		}
+	}
+	if v < bounds.min || v > bounds.max {
+		return 0, errors.Errorf("%d is out of the range %d-%d", v, bounds.min, bounds.max)
+	}
+	return v, nil
+}
+
+// Next returns the next activation of the schedule after t,
+// the zero time if there is none within five years or after the last year
+func (s *extendedSchedule) Next(t time.Time) time.Time {
+	// start at the upcoming second
+	t = t.Add(1*time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+
+	// whether a field has been incremented, resetting the smaller ones
+	added := false
+
+	yearLimit := t.Year() + 5
+	if s.years != nil {
+		yearLimit = s.lastYear
+	}
+
+WRAP:
+	for !s.yearMatches(t.Year()) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+		}
+		t = t.AddDate(1, 0, 0)
+		if t.Year() > yearLimit {
+			return time.Time{}
+		}
+	}
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.Month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// matches reports if every field of the schedule matches the wall clock time t
+func (s *extendedSchedule) matches(t time.Time) bool {
+	return s.yearMatches(t.Year()) &&
+		1<<uint(t.Month())&s.Month > 0 &&
+		s.dayMatches(t) &&
+		1<<uint(t.Hour())&s.Hour > 0 &&
+		1<<uint(t.Minute())&s.Minute > 0 &&
+		1<<uint(t.Second())&s.Second > 0
+}
+
+// yearMatches reports if the schedule activates in a year
+func (s *extendedSchedule) yearMatches(year int) bool {
+	return s.years == nil || s.years[year]
+}
+
+// dayMatches reports if the schedule activates on the day of t,
+// as in the cron engine, when both day fields are restricted either one matches
+func (s *extendedSchedule) dayMatches(t time.Time) bool {
+	domStar := s.Dom&starBit > 0
+	dowStar := s.Dow&starBit > 0
+	if domStar || dowStar {
+		return s.domMatches(t) && s.dowMatches(t)
+	}
+	return s.domMatches(t) || s.dowMatches(t)
+}
+
+// domMatches reports if the day of month field matches t
+func (s *extendedSchedule) domMatches(t time.Time) bool {
+	if 1<<uint(t.Day())&s.Dom > 0 {
+		return true
+	}
+
+	last := daysIn(t)
+	for _, offset := range s.lastDayOffsets {
+		if t.Day() == last-offset {
+			return true
+		}
+	}
+	if s.lastWeekday && t.Day() == nearestWeekday(t, last) {
+		return true
+	}
+	for _, day := range s.nearestWeekdays {
+		if day <= last && t.Day() == nearestWeekday(t, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// dowMatches reports if the day of week field matches t
+func (s *extendedSchedule) dowMatches(t time.Time) bool {
+	weekday := int(t.Weekday())
+	if 1<<uint(weekday)&s.Dow > 0 {
+		return true
+	}
+
+	for _, last := range s.lastWeekdays {
+		if weekday == last && t.Day()+7 > daysIn(t) {
+			return true
+		}
+	}
+	for _, n := range s.nthWeekdays {
+		if weekday == n.weekday && (t.Day()-1)/7+1 == n.nth {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days in the month of t
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday (monday to friday) nearest to a day in the month of t,
+// without leaving the month
+func nearestWeekday(t time.Time, day int) int {
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == daysIn(t) {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -0,0 +1,76 @@
+package grontab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestExtendedSchedule(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string][]string{
+		// the last day of the month, and 3 days before it
+		"0 0 23 L * *":  {"2026-01-31T23:00:00Z", "2026-02-28T23:00:00Z", "2026-03-31T23:00:00Z"},
+		"0 0 8 L-3 * *": {"2026-01-28T08:00:00Z", "2026-02-25T08:00:00Z", "2026-03-28T08:00:00Z"},
+		// the last weekday of the month, 2026-05-31 is a Sunday
+		"0 0 18 LW 5 *": {"2026-05-29T18:00:00Z", "2027-05-31T18:00:00Z", "2028-05-31T18:00:00Z"},
+		// the weekday nearest to the 15th, 2026-02-15 is a Sunday and 2026-03-15 too
+		"0 0 9 15W * *": {"2026-01-15T09:00:00Z", "2026-02-16T09:00:00Z", "2026-03-16T09:00:00Z"},
+		// the weekday nearest to the 1st doesn't leave the month, 2026-08-01 is a Saturday
+		"0 0 9 1W 8 *": {"2026-08-03T09:00:00Z", "2027-08-02T09:00:00Z", "2028-08-01T09:00:00Z"},
+		// the second Tuesday and the last Friday of the month
+		"0 0 10 ? * 2#2": {"2026-01-13T10:00:00Z", "2026-02-10T10:00:00Z", "2026-03-10T10:00:00Z"},
+		"0 0 0 * * FRIL": {"2026-01-30T00:00:00Z", "2026-02-27T00:00:00Z", "2026-03-27T00:00:00Z"},
+		// only in 2027 and 2028
+		"0 30 9 1 1 * 2027-2028": {"2027-01-01T09:30:00Z", "2028-01-01T09:30:00Z"},
+		// the plain fields are unchanged
+		"0 30 6 * * 1-5": {"2026-01-01T06:30:00Z", "2026-01-02T06:30:00Z", "2026-01-05T06:30:00Z"},
+	}
+	for expr, expected := range cases {
+		times, err := PreviewSchedule(expr, from, 3, "UTC")
+		if err != nil {
+			t.Errorf("expected PreviewSchedule('%s') to succeed, got %v", expr, err)
+			continue
+		}
+		if len(times) != len(expected) {
+			t.Errorf("expected '%s' to activate at %v, got %v", expr, expected, times)
+			continue
+		}
+		for i := range times {
+			if times[i].Format(time.RFC3339) != expected[i] {
+				t.Errorf("expected activation %d of '%s' to be %s, got %s", i, expr, expected[i], times[i].Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+func TestExtendedScheduleFieldErrors(t *testing.T) {
+	cases := map[string]string{
+		"0 61 * * * *":       "minute",
+		"0 0 0 L-40 * *":     "day-of-month",
+		"0 0 0 32W * *":      "day-of-month",
+		"0 0 0 * jan-foo *":  "month",
+		"0 0 0 ? * 1#6":      "day-of-week",
+		"0 0 0 ? * 9L":       "day-of-week",
+		"0 0 0 * * * 1800":   "year",
+		"0 0 0 * * * 2030-1": "year",
+	}
+	for expr, fieldName := range cases {
+		_, err := parseSchedule(expr)
+		fieldErr, ok := errors.Cause(err).(*FieldError)
+		if !ok {
+			t.Errorf("expected parseSchedule('%s') to return a *FieldError, got %v", expr, err)
+			continue
+		}
+		if fieldErr.Field != fieldName {
+			t.Errorf("expected parseSchedule('%s') to point at the %s field, got %v", expr, fieldName, fieldErr)
+		}
+	}
+
+	_, err := parseSchedule("0 0 0 * * * 2027 extra")
+	if err == nil {
+		t.Errorf("expected parseSchedule() to refuse more than 7 fields")
+	}
+}
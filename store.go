@@ -0,0 +1,119 @@
+package grontab
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by a store when a record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Store defines a persistence backend for grontab
+type Store interface {
+	// View runs fn inside a read-only transaction
+	View(fn func(tx Tx) error) error
+	// Update runs fn inside a read-write transaction,
+	// the transaction is committed only if fn returns nil
+	Update(fn func(tx Tx) error) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// Tx defines the operations available inside a store transaction
+type Tx interface {
+	// GetJob returns the job with the given id or ErrNotFound
+	GetJob(id string) (Job, error)
+	// PutJob inserts or replaces a job
+	PutJob(job Job) error
+	// DeleteJob removes the job with the given id or returns ErrNotFound
+	DeleteJob(id string) error
+	// Jobs returns all the jobs in the store
+	Jobs() ([]Job, error)
+	// JobsAt returns the jobs stored at a schedule, looked up by the Schedule index
+	JobsAt(schedule string) ([]Job, error)
+	// EnabledJobs returns the enabled jobs, looked up by the Enabled index
+	EnabledJobs() ([]Job, error)
+
+	// PutRun inserts or replaces a run in the history
+	PutRun(run Run) error
+	// DeleteRun removes a run from the history
+	DeleteRun(id string) error
+	// Runs returns the history of a job, oldest first
+	Runs(jobID string) ([]Run, error)
+
+	// GetCalendar returns the calendar with the given name or ErrNotFound
+	GetCalendar(name string) (Calendar, error)
+	// PutCalendar inserts or replaces a calendar
+	PutCalendar(calendar Calendar) error
+	// DeleteCalendar removes the calendar with the given name or returns ErrNotFound
+	DeleteCalendar(name string) error
+	// Calendars returns all the calendars in the store
+	Calendars() ([]Calendar, error)
+
+	// GetWorkflow returns the workflow with the given name or ErrNotFound
+	GetWorkflow(name string) (Workflow, error)
+	// PutWorkflow inserts or replaces a workflow
+	PutWorkflow(workflow Workflow) error
+	// DeleteWorkflow removes the workflow with the given name or returns ErrNotFound
+	DeleteWorkflow(name string) error
+	// Workflows returns all the workflows in the store
+	Workflows() ([]Workflow, error)
+
+	// PutWorkflowRun inserts or replaces a run in the history of a workflow
+	PutWorkflowRun(run WorkflowRun) error
+	// DeleteWorkflowRun removes a run from the history of a workflow
+	DeleteWorkflowRun(id string) error
+	// WorkflowRuns returns the history of a workflow, oldest first
+	WorkflowRuns(workflow string) ([]WorkflowRun, error)
+
+	// SchemaVersion returns the version of the stored schema,
+	// 0 for an empty store and 1 for a store written before the schema was versioned
+	SchemaVersion() (int, error)
+	// SetSchemaVersion records the version of the stored schema
+	SetSchemaVersion(version int) error
+}
+
+// RunStatus defines the outcome of a run
+type RunStatus string
+
+// the possible outcomes of a run
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunSkipped   RunStatus = "skipped"
+)
+
+// Run defines a single execution of a job
+type Run struct {
+	ID       string `storm:"id"`
+	JobID    string `storm:"index"`
+	Schedule string
+	Start    time.Time
+	End      time.Time
+	Status   RunStatus
+	Output   string
+	Error    string
+	// Reason tells why a skipped run didn't execute, or why a run was deferred
+	Reason string
+	// Result is the result of the run, when the job declares one
+	Result string
+	// ExitCode is the exit code of the task, -1 if it didn't exit by itself
+	ExitCode int
+	// TimedOut tells if the task was killed by its timeout
+	TimedOut bool
+}
+
+// the number of runs kept per job when Config.HistorySize is not set
+const defaultHistorySize = 100
+
+// sortRuns sorts runs by start time, oldest first
+func sortRuns(runs []Run) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Start.Equal(runs[j].Start) {
+			return runs[i].ID < runs[j].ID
+		}
+		return runs[i].Start.Before(runs[j].Start)
+	})
+}
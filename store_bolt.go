@@ -0,0 +1,342 @@
+package grontab
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore is the default store, it keeps one record per job in a bbolt file through storm
+type boltStore struct {
+	db     *storm.DB
+	bucket string
+	path   string
+}
+
+// boltTx is a transaction on a boltStore
+type boltTx struct {
+	tx     *bolt.Tx
+	node   storm.Node
+	bucket string
+}
+
+// the key of the schema version record in the bucket
+const boltSchemaKey = "__grontab_schema"
+
+// the time given to the opening of a bbolt file locked by another process, like a running grontab
+const boltOpenTimeout = time.Second
+
+// NewBoltStore opens (or creates) a bbolt file at path and keeps the jobs in bucket
+func NewBoltStore(path string, bucket string) (Store, error) {
+	return openBoltStore(path, bucket, false)
+}
+
+// openBoltStore opens a bbolt file at path, read only if readOnly,
+// it fails after boltOpenTimeout if the file is locked by another process
+func openBoltStore(path string, bucket string, readOnly bool) (Store, error) {
+	db, err := storm.Open(path, storm.BoltOptions(0600, &bolt.Options{Timeout: boltOpenTimeout, ReadOnly: readOnly}))
+	if err == bolt.ErrTimeout {
+		return nil, errors.Wrap(err, "Error Opening bolt store: "+path+" is in use by another process")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Opening bolt store")
+	}
+	return &boltStore{db: db, bucket: bucket, path: path}, nil
+}
+
+func (s *boltStore) View(fn func(tx Tx) error) error {
+	return s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return fn(s.newTx(tx))
+	})
+}
+
+func (s *boltStore) Update(fn func(tx Tx) error) error {
+	return s.db.Bolt.Update(func(tx *bolt.Tx) error {
+		return fn(s.newTx(tx))
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltStore) newTx(tx *bolt.Tx) *boltTx {
+	return &boltTx{tx: tx, node: s.db.WithTransaction(tx), bucket: s.bucket}
+}
+
+// backup writes a consistent copy of the bbolt file next to it
+func (s *boltStore) backup(version int) (string, error) {
+	path := backupPath(s.path, version)
+	err := s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Error Backing up bolt store")
+	}
+	return path, nil
+}
+
+func (t *boltTx) GetJob(id string) (Job, error) {
+	var job Job
+	err := t.node.From(t.bucket).One("ID", id, &job)
+	if err == storm.ErrNotFound {
+		return job, ErrNotFound
+	}
+	return job, err
+}
+
+func (t *boltTx) PutJob(job Job) error {
+	return t.node.From(t.bucket).Save(&job)
+}
+
+func (t *boltTx) DeleteJob(id string) error {
+	err := t.node.From(t.bucket).DeleteStruct(&Job{ID: id})
+	if err == storm.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (t *boltTx) Jobs() ([]Job, error) {
+	var jobs []Job
+	err := t.node.From(t.bucket).All(&jobs)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (t *boltTx) JobsAt(schedule string) ([]Job, error) {
+	return t.findJobs("Schedule", schedule)
+}
+
+func (t *boltTx) EnabledJobs() ([]Job, error) {
+	return t.findJobs("Enabled", true)
+}
+
+// findJobs returns the jobs whose indexed field has the value, none if no job has it
+func (t *boltTx) findJobs(field string, value interface{}) ([]Job, error) {
+	var jobs []Job
+	err := t.node.From(t.bucket).Find(field, value, &jobs)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (t *boltTx) PutRun(run Run) error {
+	return t.node.From(t.bucket).Save(&run)
+}
+
+func (t *boltTx) DeleteRun(id string) error {
+	err := t.node.From(t.bucket).DeleteStruct(&Run{ID: id})
+	if err == storm.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (t *boltTx) Runs(jobID string) ([]Run, error) {
+	var runs []Run
+	err := t.node.From(t.bucket).Find("JobID", jobID, &runs)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+func (t *boltTx) GetCalendar(name string) (Calendar, error) {
+	var calendar Calendar
+	err := t.node.From(t.bucket).One("Name", name, &calendar)
+	if err == storm.ErrNotFound {
+		return calendar, ErrNotFound
+	}
+	return calendar, err
+}
+
+func (t *boltTx) PutCalendar(calendar Calendar) error {
+	return t.node.From(t.bucket).Save(&calendar)
+}
+
+func (t *boltTx) DeleteCalendar(name string) error {
+	err := t.node.From(t.bucket).DeleteStruct(&Calendar{Name: name})
+	if err == storm.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (t *boltTx) Calendars() ([]Calendar, error) {
+	var calendars []Calendar
+	err := t.node.From(t.bucket).All(&calendars)
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+func (t *boltTx) GetWorkflow(name string) (Workflow, error) {
+	var workflow Workflow
+	err := t.node.From(t.bucket).One("Name", name, &workflow)
+	if err == storm.ErrNotFound {
+		return workflow, ErrNotFound
+	}
+	return workflow, err
+}
+
+func (t *boltTx) PutWorkflow(workflow Workflow) error {
+	return t.node.From(t.bucket).Save(&workflow)
+}
+
+func (t *boltTx) DeleteWorkflow(name string) error {
+	err := t.node.From(t.bucket).DeleteStruct(&Workflow{Name: name})
+	if err == storm.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (t *boltTx) Workflows() ([]Workflow, error) {
+	var workflows []Workflow
+	err := t.node.From(t.bucket).All(&workflows)
+	if err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+func (t *boltTx) PutWorkflowRun(run WorkflowRun) error {
+	return t.node.From(t.bucket).Save(&run)
+}
+
+func (t *boltTx) DeleteWorkflowRun(id string) error {
+	err := t.node.From(t.bucket).DeleteStruct(&WorkflowRun{ID: id})
+	if err == storm.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (t *boltTx) WorkflowRuns(workflow string) ([]WorkflowRun, error) {
+	var runs []WorkflowRun
+	err := t.node.From(t.bucket).Find("Workflow", workflow, &runs)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortWorkflowRuns(runs)
+	return runs, nil
+}
+
+func (t *boltTx) SchemaVersion() (int, error) {
+	var version int
+	err := t.node.Get(t.bucket, boltSchemaKey, &version)
+	if err == nil {
+		return version, nil
+	}
+	if err != storm.ErrNotFound {
+		return 0, err
+	}
+
+	// no version record, the bucket is either new or written
+	// before the schema was versioned
+	b := t.tx.Bucket([]byte(t.bucket))
+	if b == nil {
+		return 0, nil
+	}
+	if k, _ := b.Cursor().First(); k == nil {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (t *boltTx) SetSchemaVersion(version int) error {
+	return t.node.Set(t.bucket, boltSchemaKey, version)
+}
+
+// migrateLegacyGroups converts the jobgroups keyed by schedule (gid),
+// stored by the previous versions of grontab, into one record per job
+func (t *boltTx) migrateLegacyGroups() error {
+	b := t.tx.Bucket([]byte(t.bucket))
+	if b == nil {
+		return nil
+	}
+
+	groups := make(map[string]map[string]jobDetails)
+	cur := b.Cursor()
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		kstr := string(k)
+		// ignores the storm_metadata key, the grontab records and the nested buckets
+		if strings.HasPrefix(kstr, "__") || v == nil {
+			continue
+		}
+		jg := make(map[string]jobDetails)
+		err := t.node.Codec().Unmarshal(v, &jg)
+		if err != nil {
+			return errors.Wrap(err, "Error Migrating object from storage for gid: "+kstr)
+		}
+		groups[kstr] = jg
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	migrated := make(map[string]bool)
+	for _, gid := range scheduleKeys(groups) {
+		jg := groups[gid]
+		for _, jid := range jobKeys(jg) {
+			// a job found in more than one schedule is kept at the first one
+			if migrated[jid] {
+				continue
+			}
+			migrated[jid] = true
+
+			err := t.PutJob(Job{ID: jid, Schedule: normalizeSchedule(gid), Task: jg[jid].Task, Enabled: jg[jid].Enabled})
+			if err != nil {
+				return errors.Wrap(err, "Error Migrating job "+jid)
+			}
+		}
+
+		err := b.Delete([]byte(gid))
+		if err != nil {
+			return errors.Wrap(err, "Error Migrating object from storage for gid: "+gid)
+		}
+	}
+
+	log.Printf("Migrated %d jobs from %d schedules to the job storage layout", len(migrated), len(groups))
+	return nil
+}
+
+// scheduleKeys returns the schedules (gid) of the jobgroups in ascending order
+func scheduleKeys(groups map[string]map[string]jobDetails) []string {
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jobKeys returns the job ids (jid) of a jobgroup in ascending order
+func jobKeys(jg map[string]jobDetails) []string {
+	var keys []string
+	for k := range jg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
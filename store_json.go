@@ -0,0 +1,170 @@
+package grontab
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// jsonFile is the human-readable layout of a JSON store file
+type jsonFile struct {
+	Schema int               `json:"schema"`
+	Jobs   []json.RawMessage `json:"jobs"`
+	Runs   []json.RawMessage `json:"runs"`
+	// Calendars is omitted from the files without calendars
+	Calendars []json.RawMessage `json:"calendars,omitempty"`
+	// Workflows and WorkflowRuns are omitted from the files without workflows
+	Workflows    []json.RawMessage `json:"workflows,omitempty"`
+	WorkflowRuns []json.RawMessage `json:"workflowRuns,omitempty"`
+}
+
+// jsonStore is a memoryStore persisted to a JSON file
+type jsonStore struct {
+	*memoryStore
+	path string
+}
+
+// NewJSONStore returns a store that keeps the jobs in a human-readable JSON file at path,
+// the whole file is rewritten atomically at each committed transaction
+func NewJSONStore(path string) (Store, error) {
+	state := newMemoryState()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "Error Opening json store")
+	}
+
+	if len(content) > 0 {
+		var file jsonFile
+		err = json.Unmarshal(content, &file)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error Opening json store")
+		}
+
+		// a file without a schema was written before the schema was versioned
+		state.schema = file.Schema
+		if state.schema == 0 {
+			state.schema = 1
+		}
+
+		for _, raw := range file.Jobs {
+			var job Job
+			err = json.Unmarshal(raw, &job)
+			if err != nil {
+				return nil, errors.Wrap(err, "Error Opening json store")
+			}
+			state.jobs[job.ID], _ = json.Marshal(job)
+		}
+		for _, raw := range file.Runs {
+			var run Run
+			err = json.Unmarshal(raw, &run)
+			if err != nil {
+				return nil, errors.Wrap(err, "Error Opening json store")
+			}
+			state.runs[run.ID], _ = json.Marshal(run)
+		}
+		for _, raw := range file.Calendars {
+			var calendar Calendar
+			err = json.Unmarshal(raw, &calendar)
+			if err != nil {
+				return nil, errors.Wrap(err, "Error Opening json store")
+			}
+			state.calendars[calendar.Name], _ = json.Marshal(calendar)
+		}
+		for _, raw := range file.Workflows {
+			var workflow Workflow
+			err = json.Unmarshal(raw, &workflow)
+			if err != nil {
+				return nil, errors.Wrap(err, "Error Opening json store")
+			}
+			state.workflows[workflow.Name], _ = json.Marshal(workflow)
+		}
+		for _, raw := range file.WorkflowRuns {
+			var run WorkflowRun
+			err = json.Unmarshal(raw, &run)
+			if err != nil {
+				return nil, errors.Wrap(err, "Error Opening json store")
+			}
+			state.workflowRuns[run.ID], _ = json.Marshal(run)
+		}
+	}
+
+	return &jsonStore{
+		memoryStore: &memoryStore{
+			state: state,
+			persist: func(state memoryState) error {
+				return writeJSONFile(path, state)
+			},
+		},
+		path: path,
+	}, nil
+}
+
+// backup copies the JSON file next to it
+func (s *jsonStore) backup(version int) (string, error) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", errors.Wrap(err, "Error Backing up json store")
+	}
+	path := backupPath(s.path, version)
+	err = ioutil.WriteFile(path, content, 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "Error Backing up json store")
+	}
+	return path, nil
+}
+
+// writeJSONFile writes the state to a temporary file and renames it over path
+func writeJSONFile(path string, state memoryState) error {
+	file := jsonFile{
+		Schema:       state.schema,
+		Jobs:         sortedRecords(state.jobs),
+		Runs:         sortedRecords(state.runs),
+		Calendars:    sortedRecords(state.calendars),
+		Workflows:    sortedRecords(state.workflows),
+		WorkflowRuns: sortedRecords(state.workflowRuns),
+	}
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Error Writing json store")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Error Writing json store")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(content, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "Error Writing json store")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), path), "Error Writing json store")
+}
+
+// sortedRecords returns the encoded records ordered by key
+func sortedRecords(records map[string][]byte) []json.RawMessage {
+	var keys []string
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	raws := make([]json.RawMessage, 0, len(keys))
+	for _, k := range keys {
+		raws = append(raws, records[k])
+	}
+	return raws
+}
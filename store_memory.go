@@ -0,0 +1,360 @@
+package grontab
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// memoryStore keeps the jobs in memory, the records are kept encoded
+// so that callers never share memory with the store
+type memoryStore struct {
+	mu    sync.RWMutex
+	state memoryState
+	// persist, if set, is invoked with the new state before each commit
+	persist func(state memoryState) error
+}
+
+// memoryState is the content of a memoryStore
+type memoryState struct {
+	schema       int
+	jobs         map[string][]byte
+	runs         map[string][]byte
+	calendars    map[string][]byte
+	workflows    map[string][]byte
+	workflowRuns map[string][]byte
+}
+
+// memoryTx is a transaction on a memoryStore
+type memoryTx struct {
+	state    memoryState
+	writable bool
+}
+
+// errReadOnlyTx is returned when writing inside a read-only transaction
+var errReadOnlyTx = errors.New("write inside a read-only transaction")
+
+// NewMemoryStore returns an empty store that lives only in memory
+func NewMemoryStore() Store {
+	return &memoryStore{state: newMemoryState()}
+}
+
+func newMemoryState() memoryState {
+	return memoryState{
+		jobs:         make(map[string][]byte),
+		runs:         make(map[string][]byte),
+		calendars:    make(map[string][]byte),
+		workflows:    make(map[string][]byte),
+		workflowRuns: make(map[string][]byte),
+	}
+}
+
+func (s *memoryStore) View(fn func(tx Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(&memoryTx{state: s.state})
+}
+
+func (s *memoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// work on a copy of the state, swapped in only on success
+	tx := &memoryTx{state: s.state.clone(), writable: true}
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+	if s.persist != nil {
+		err = s.persist(tx.state)
+		if err != nil {
+			return err
+		}
+	}
+	s.state = tx.state
+	return nil
+}
+
+// Close is a no-op, the content is kept as long as the store is referenced
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+func (m memoryState) clone() memoryState {
+	c := newMemoryState()
+	c.schema = m.schema
+	for k, v := range m.jobs {
+		c.jobs[k] = v
+	}
+	for k, v := range m.runs {
+		c.runs[k] = v
+	}
+	for k, v := range m.calendars {
+		c.calendars[k] = v
+	}
+	for k, v := range m.workflows {
+		c.workflows[k] = v
+	}
+	for k, v := range m.workflowRuns {
+		c.workflowRuns[k] = v
+	}
+	return c
+}
+
+func (t *memoryTx) GetJob(id string) (Job, error) {
+	var job Job
+	raw, ok := t.state.jobs[id]
+	if !ok {
+		return job, ErrNotFound
+	}
+	err := json.Unmarshal(raw, &job)
+	return job, err
+}
+
+func (t *memoryTx) PutJob(job Job) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	t.state.jobs[job.ID] = raw
+	return nil
+}
+
+func (t *memoryTx) DeleteJob(id string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	if _, ok := t.state.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(t.state.jobs, id)
+	return nil
+}
+
+func (t *memoryTx) Jobs() ([]Job, error) {
+	var ids []string
+	for id := range t.state.jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var jobs []Job
+	for _, id := range ids {
+		job, err := t.GetJob(id)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (t *memoryTx) JobsAt(schedule string) ([]Job, error) {
+	return t.findJobs(func(job Job) bool { return job.Schedule == schedule })
+}
+
+func (t *memoryTx) EnabledJobs() ([]Job, error) {
+	return t.findJobs(func(job Job) bool { return job.Enabled })
+}
+
+// findJobs returns the jobs matching a condition, the memory store having no indexes
+func (t *memoryTx) findJobs(matches func(Job) bool) ([]Job, error) {
+	jobs, err := t.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	var found []Job
+	for _, job := range jobs {
+		if matches(job) {
+			found = append(found, job)
+		}
+	}
+	return found, nil
+}
+
+func (t *memoryTx) PutRun(run Run) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	raw, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	t.state.runs[run.ID] = raw
+	return nil
+}
+
+func (t *memoryTx) DeleteRun(id string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	if _, ok := t.state.runs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(t.state.runs, id)
+	return nil
+}
+
+func (t *memoryTx) Runs(jobID string) ([]Run, error) {
+	var runs []Run
+	for _, raw := range t.state.runs {
+		var run Run
+		err := json.Unmarshal(raw, &run)
+		if err != nil {
+			return nil, err
+		}
+		if run.JobID == jobID {
+			runs = append(runs, run)
+		}
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+func (t *memoryTx) GetCalendar(name string) (Calendar, error) {
+	var calendar Calendar
+	raw, ok := t.state.calendars[name]
+	if !ok {
+		return calendar, ErrNotFound
+	}
+	err := json.Unmarshal(raw, &calendar)
+	return calendar, err
+}
+
+func (t *memoryTx) PutCalendar(calendar Calendar) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	raw, err := json.Marshal(calendar)
+	if err != nil {
+		return err
+	}
+	t.state.calendars[calendar.Name] = raw
+	return nil
+}
+
+func (t *memoryTx) DeleteCalendar(name string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	if _, ok := t.state.calendars[name]; !ok {
+		return ErrNotFound
+	}
+	delete(t.state.calendars, name)
+	return nil
+}
+
+func (t *memoryTx) Calendars() ([]Calendar, error) {
+	var calendars []Calendar
+	for _, raw := range sortedRecords(t.state.calendars) {
+		var calendar Calendar
+		err := json.Unmarshal(raw, &calendar)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, calendar)
+	}
+	return calendars, nil
+}
+
+func (t *memoryTx) GetWorkflow(name string) (Workflow, error) {
+	var workflow Workflow
+	raw, ok := t.state.workflows[name]
+	if !ok {
+		return workflow, ErrNotFound
+	}
+	err := json.Unmarshal(raw, &workflow)
+	return workflow, err
+}
+
+func (t *memoryTx) PutWorkflow(workflow Workflow) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	raw, err := json.Marshal(workflow)
+	if err != nil {
+		return err
+	}
+	t.state.workflows[workflow.Name] = raw
+	return nil
+}
+
+func (t *memoryTx) DeleteWorkflow(name string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	if _, ok := t.state.workflows[name]; !ok {
+		return ErrNotFound
+	}
+	delete(t.state.workflows, name)
+	return nil
+}
+
+func (t *memoryTx) Workflows() ([]Workflow, error) {
+	var workflows []Workflow
+	for _, raw := range sortedRecords(t.state.workflows) {
+		var workflow Workflow
+		err := json.Unmarshal(raw, &workflow)
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, workflow)
+	}
+	return workflows, nil
+}
+
+func (t *memoryTx) PutWorkflowRun(run WorkflowRun) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	raw, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	t.state.workflowRuns[run.ID] = raw
+	return nil
+}
+
+func (t *memoryTx) DeleteWorkflowRun(id string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	if _, ok := t.state.workflowRuns[id]; !ok {
+		return ErrNotFound
+	}
+	delete(t.state.workflowRuns, id)
+	return nil
+}
+
+func (t *memoryTx) WorkflowRuns(workflow string) ([]WorkflowRun, error) {
+	var runs []WorkflowRun
+	for _, raw := range t.state.workflowRuns {
+		var run WorkflowRun
+		err := json.Unmarshal(raw, &run)
+		if err != nil {
+			return nil, err
+		}
+		if run.Workflow == workflow {
+			runs = append(runs, run)
+		}
+	}
+	sortWorkflowRuns(runs)
+	return runs, nil
+}
+
+func (t *memoryTx) SchemaVersion() (int, error) {
+	return t.state.schema, nil
+}
+
+func (t *memoryTx) SetSchemaVersion(version int) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	t.state.schema = version
+	return nil
+}
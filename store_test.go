@@ -0,0 +1,141 @@
+package grontab
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm"
+)
+
+// the backends the whole test suite runs against
+var testBackends = []string{"bolt", "memory", "json"}
+
+// the backend used by the running test suite
+var testBackend string
+
+func TestMain(m *testing.M) {
+	code := 0
+	for _, backend := range testBackends {
+		testBackend = backend
+		log.Printf("running the test suite against the %s store", backend)
+		if result := m.Run(); result != 0 {
+			code = result
+		}
+	}
+	os.Remove("./db.db")
+	os.Exit(code)
+}
+
+// testInit initializes grontab with the store of the backend under test
+func testInit(config Config) error {
+	switch testBackend {
+	case "memory":
+		config.Store = NewMemoryStore()
+	case "json":
+		s, err := NewJSONStore(config.PersistencePath)
+		if err != nil {
+			return err
+		}
+		config.Store = s
+	}
+	return Init(config)
+}
+
+func TestStoreTransactionRollback(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	defer Stop()
+
+	err := store.Update(func(tx Tx) error {
+		err := tx.PutJob(Job{ID: "rollback", Schedule: "*/10 * * * * *", Task: "echo 'ciaone'"})
+		if err != nil {
+			return err
+		}
+		return ErrNotFound
+	})
+	if err != ErrNotFound {
+		t.Errorf("expected Update() to return the error of the transaction, got %v", err)
+	}
+
+	err = store.View(func(tx Tx) error {
+		_, err := tx.GetJob("rollback")
+		return err
+	})
+	if err != ErrNotFound {
+		t.Errorf("expected a failed transaction to be rolled back, got %v", err)
+	}
+}
+
+func TestStoreHistory(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true, HistorySize: 2})
+	defer Stop()
+
+	start := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		recordRun(Run{ID: id, JobID: "job", Start: start.Add(time.Duration(i) * time.Second), Status: RunSucceeded})
+	}
+
+	runs, err := History("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != "b" || runs[1].ID != "c" {
+		t.Errorf("expected History() to keep the 2 most recent runs, got %v", runs)
+	}
+}
+
+func TestBoltStoreMigratesLegacyGroups(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	// write the jobgroups keyed by schedule, as the previous versions did
+	legacy, err := storm.Open("./db.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Set("jobs", "*/10  *  * * * *", map[string]jobDetails{"a": {Task: "echo 'a'", Enabled: true}})
+	legacy.Set("jobs", "*/10 * * * * *", map[string]jobDetails{"b": {Task: "echo 'b'", Enabled: true}})
+	legacy.Set("jobs", "*/20 * * * * *", map[string]jobDetails{"a": {Task: "echo 'a'", Enabled: true}})
+	legacy.Close()
+
+	err = Init(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Stop()
+
+	grontabMap := List()
+	if len(grontabMap) != 1 || len(grontabMap["*/10 * * * * *"]) != 2 {
+		t.Errorf("expected the legacy jobgroups to be migrated to normalized schedules, got %v", grontabMap)
+	}
+
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected the migrated storage to be consistent, found %v", issues)
+	}
+
+	backups, _ := filepath.Glob("./db.db.v1.*.bak")
+	if len(backups) != 1 {
+		t.Errorf("expected a backup of the storage taken before migrating, found %v", backups)
+	}
+	for _, backup := range backups {
+		os.Remove(backup)
+	}
+}
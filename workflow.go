@@ -0,0 +1,425 @@
+package grontab
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/damdo/randid"
+	"github.com/pkg/errors"
+	"github.com/wgliang/cron"
+)
+
+// the trigger rules of the workflow nodes, telling when a node runs once its upstream nodes are done
+const (
+	// TriggerAllSuccess runs a node when all its upstream nodes succeeded, the default
+	TriggerAllSuccess = "all-success"
+	// TriggerAllDone runs a node when all its upstream nodes are done, whatever their outcome
+	TriggerAllDone = "all-done"
+	// TriggerAnyFailed runs a node when any of its upstream nodes failed
+	TriggerAnyFailed = "any-failed"
+)
+
+// the prefix of the cron entries of the workflows, followed by the workflow name
+const workflowEntryPrefix = "workflow:"
+
+// Workflow defines a DAG of jobs run at a schedule, each node running
+// its job when its upstream nodes are done and its trigger rule is met
+type Workflow struct {
+	Name string `storm:"id"`
+	// Schedule triggers the workflow, if empty the workflow runs only through RunWorkflow
+	Schedule string
+	Nodes    []WorkflowNode
+}
+
+// WorkflowNode defines a node of a workflow, running the job JobID whether the job is enabled or not,
+// so that the jobs run only by workflows can be disabled at their own schedule
+type WorkflowNode struct {
+	JobID string
+	// DependsOn are the jobs of the upstream nodes
+	DependsOn []string
+	// Trigger is the trigger rule of the node, TriggerAllSuccess if empty
+	Trigger string
+}
+
+// WorkflowRun defines a single execution of a workflow, failed if any of its nodes failed
+type WorkflowRun struct {
+	ID       string `storm:"id"`
+	Workflow string `storm:"index"`
+	Start    time.Time
+	End      time.Time
+	Status   RunStatus
+	Nodes    []NodeRun
+}
+
+// NodeRun defines the outcome of a node in a workflow run,
+// RunID is the run of the job in its history, empty if the node was skipped
+type NodeRun struct {
+	JobID  string
+	RunID  string
+	Start  time.Time
+	End    time.Time
+	Status RunStatus
+	Reason string
+}
+
+// AddWorkflow adds a workflow to the storage and schedules it, replacing the one with the same name
+func AddWorkflow(workflow Workflow) error {
+	return addWorkflow(workflow)
+}
+
+// RemoveWorkflow removes a workflow and stops its schedule
+func RemoveWorkflow(name string) error {
+	return removeWorkflow(name)
+}
+
+// Workflows returns the workflows in the storage
+func Workflows() ([]Workflow, error) {
+	return workflows()
+}
+
+// RunWorkflow runs a workflow now, waits for its nodes and returns the recorded run
+func RunWorkflow(name string) (WorkflowRun, error) {
+	return runWorkflow(name)
+}
+
+// WorkflowHistory returns the runs of a workflow, oldest first
+func WorkflowHistory(name string) ([]WorkflowRun, error) {
+	return workflowHistory(name)
+}
+
+func addWorkflow(workflow Workflow) error {
+	workflow.Schedule = normalizeSchedule(workflow.Schedule)
+	err := store.Update(func(tx Tx) error {
+		err := workflow.validate(tx)
+		if err != nil {
+			return err
+		}
+		return tx.PutWorkflow(workflow)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error Adding workflow "+workflow.Name)
+	}
+
+	// the storage is now consistent, align the cron engine to it
+	unregisterWorkflow(workflow.Name)
+	err = registerWorkflow(workflow)
+	if err != nil {
+		return err
+	}
+	log.Printf(green("ADD WFL : {%s %d nodes} to ['%s']"), workflow.Name, len(workflow.Nodes), workflow.Schedule)
+	return nil
+}
+
+func removeWorkflow(name string) error {
+	err := store.Update(func(tx Tx) error {
+		return tx.DeleteWorkflow(name)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to Remove workflow "+name)
+	}
+	unregisterWorkflow(name)
+	log.Printf(yellow("REM WFL : {%s}"), name)
+	return nil
+}
+
+func workflows() ([]Workflow, error) {
+	var workflows []Workflow
+	err := store.View(func(tx Tx) error {
+		var err error
+		workflows, err = tx.Workflows()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Getting workflows")
+	}
+	return workflows, nil
+}
+
+func workflowHistory(name string) ([]WorkflowRun, error) {
+	var runs []WorkflowRun
+	err := store.View(func(tx Tx) error {
+		var err error
+		runs, err = tx.WorkflowRuns(name)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error Getting history of workflow "+name)
+	}
+	return runs, nil
+}
+
+func runWorkflow(name string) (WorkflowRun, error) {
+	var workflow Workflow
+	jobs := make(map[string]Job)
+	err := store.View(func(tx Tx) error {
+		var err error
+		workflow, err = tx.GetWorkflow(name)
+		if err != nil {
+			return err
+		}
+		for _, node := range workflow.Nodes {
+			jobs[node.JobID], err = tx.GetJob(node.JobID)
+			if err != nil {
+				return errors.Wrap(err, "Error Getting Job "+node.JobID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return WorkflowRun{}, errors.Wrap(err, "Error Running workflow "+name)
+	}
+
+	rid, err := randid.ID()
+	if err != nil {
+		return WorkflowRun{}, err
+	}
+	run := WorkflowRun{ID: fmt.Sprintf("%s", rid), Workflow: name, Start: time.Now(), Status: RunSucceeded}
+	log.Printf(green("RUNN WFL(%s)[%s]"), run.ID, name)
+
+	// each node waits for its upstream nodes to be done, then runs or is skipped
+	run.Nodes = make([]NodeRun, len(workflow.Nodes))
+	done := make(map[string]chan struct{})
+	position := make(map[string]int)
+	for i, node := range workflow.Nodes {
+		done[node.JobID] = make(chan struct{})
+		position[node.JobID] = i
+	}
+
+	var wg sync.WaitGroup
+	for i, node := range workflow.Nodes {
+		wg.Add(1)
+		go func(i int, node WorkflowNode) {
+			defer wg.Done()
+			defer close(done[node.JobID])
+
+			var upstream []RunStatus
+			for _, dependency := range node.DependsOn {
+				<-done[dependency]
+				upstream = append(upstream, run.Nodes[position[dependency]].Status)
+			}
+
+			if !triggered(node.trigger(), upstream) {
+				now := time.Now()
+				run.Nodes[i] = NodeRun{JobID: node.JobID, Start: now, End: now, Status: RunSkipped,
+					Reason: "trigger rule " + node.trigger() + " not met"}
+				log.Printf(yellow("SKIP WFL(%s)[%s][%s]: %s"), run.ID, name, node.JobID, run.Nodes[i].Reason)
+				return
+			}
+
+			log.Printf(green("EXEC WFL(%s)[%s][%s]: %s"), run.ID, name, node.JobID, jobs[node.JobID].Task)
+			jobRun := runJob(jobs[node.JobID])
+			run.Nodes[i] = NodeRun{JobID: node.JobID, RunID: jobRun.ID, Start: jobRun.Start, End: jobRun.End, Status: jobRun.Status}
+		}(i, node)
+	}
+	wg.Wait()
+
+	run.End = time.Now()
+	for _, node := range run.Nodes {
+		if node.Status == RunFailed {
+			run.Status = RunFailed
+		}
+	}
+	recordWorkflowRun(run)
+	log.Printf(green("ENDD WFL(%s)[%s]: %s"), run.ID, name, run.Status)
+	return run, nil
+}
+
+// triggered reports if a trigger rule is met by the outcomes of the upstream nodes,
+// the nodes without upstream nodes always run
+func triggered(trigger string, upstream []RunStatus) bool {
+	if len(upstream) == 0 {
+		return true
+	}
+	switch trigger {
+	case TriggerAllDone:
+		return true
+	case TriggerAnyFailed:
+		for _, status := range upstream {
+			if status == RunFailed {
+				return true
+			}
+		}
+		return false
+	}
+	for _, status := range upstream {
+		if status != RunSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// trigger returns the trigger rule of a node
+func (node WorkflowNode) trigger() string {
+	if node.Trigger == "" {
+		return TriggerAllSuccess
+	}
+	return node.Trigger
+}
+
+// validate checks the name, the schedule and the nodes of a workflow,
+// whose jobs must exist and whose dependencies must not form a cycle
+func (workflow Workflow) validate(tx Tx) error {
+	if workflow.Name == "" {
+		return errors.New("a workflow needs a name")
+	}
+	if workflow.Schedule != "" {
+		if _, err := parseSchedule(workflow.Schedule); err != nil {
+			return err
+		}
+	}
+	if len(workflow.Nodes) == 0 {
+		return errors.New("a workflow needs at least a node")
+	}
+
+	dependencies := make(map[string][]string)
+	for _, node := range workflow.Nodes {
+		if _, ok := dependencies[node.JobID]; ok {
+			return errors.Errorf("the job %s is in more than one node", node.JobID)
+		}
+		if _, err := tx.GetJob(node.JobID); err != nil {
+			return errors.Wrap(err, "Error Getting Job "+node.JobID)
+		}
+		switch node.trigger() {
+		case TriggerAllSuccess, TriggerAllDone, TriggerAnyFailed:
+		default:
+			return errors.Errorf("invalid trigger rule '%s' of node %s", node.Trigger, node.JobID)
+		}
+		dependencies[node.JobID] = node.DependsOn
+	}
+	for jid, upstream := range dependencies {
+		for _, dependency := range upstream {
+			if _, ok := dependencies[dependency]; !ok {
+				return errors.Errorf("the node %s depends on %s, not in the workflow", jid, dependency)
+			}
+		}
+	}
+
+	// a depth first visit finds the cycles, through the nodes still being visited
+	visiting := make(map[string]bool)
+	visited := make(map[string]bool)
+	var visit func(jid string) error
+	visit = func(jid string) error {
+		if visiting[jid] {
+			return errors.Errorf("the dependencies of node %s form a cycle", jid)
+		}
+		if visited[jid] {
+			return nil
+		}
+		visiting[jid] = true
+		for _, dependency := range dependencies[jid] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		visiting[jid] = false
+		visited[jid] = true
+		return nil
+	}
+	for _, node := range workflow.Nodes {
+		if err := visit(node.JobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordWorkflowRun saves a workflow run, dropping the oldest runs of the workflow
+// beyond the configured history size
+func recordWorkflowRun(run WorkflowRun) {
+	size := grontabConfiguration.HistorySize
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+
+	err := store.Update(func(tx Tx) error {
+		err := tx.PutWorkflowRun(run)
+		if err != nil {
+			return err
+		}
+		runs, err := tx.WorkflowRuns(run.Workflow)
+		if err != nil {
+			return err
+		}
+		for len(runs) > size {
+			err = tx.DeleteWorkflowRun(runs[0].ID)
+			if err != nil {
+				return err
+			}
+			runs = runs[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(red("Error recording run %s of workflow %s: %s"), run.ID, run.Workflow, err)
+	}
+}
+
+// workflowsOf returns the names of the workflows running a job
+func workflowsOf(tx Tx, jid string) ([]string, error) {
+	workflows, err := tx.Workflows()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, workflow := range workflows {
+		for _, node := range workflow.Nodes {
+			if node.JobID == jid {
+				names = append(names, workflow.Name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// registerWorkflows schedules the workflows in the storage
+func registerWorkflows() error {
+	workflows, err := workflows()
+	if err != nil {
+		return err
+	}
+	for _, workflow := range workflows {
+		err = registerWorkflow(workflow)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	return nil
+}
+
+// registerWorkflow adds to the cron engine the run of a workflow at its schedule, if any
+func registerWorkflow(workflow Workflow) error {
+	if workflow.Schedule == "" {
+		return nil
+	}
+	schedule, err := parseSchedule(workflow.Schedule)
+	if err != nil {
+		return errors.Wrap(err, "Error Adding workflow to grontab")
+	}
+	name := workflow.Name
+	c.Schedule(schedule, cron.FuncJob(func() {
+		if _, err := runWorkflow(name); err != nil {
+			log.Println(err)
+		}
+	}), workflowEntryPrefix+name)
+	return nil
+}
+
+// unregisterWorkflow stops the schedule of a workflow
+func unregisterWorkflow(name string) {
+	c.Remove(workflowEntryPrefix + name)
+}
+
+// sortWorkflowRuns sorts workflow runs by start time, oldest first
+func sortWorkflowRuns(runs []WorkflowRun) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Start.Equal(runs[j].Start) {
+			return runs[i].ID < runs[j].ID
+		}
+		return runs[i].Start.Before(runs[j].Start)
+	})
+}
@@ -0,0 +1,100 @@
+package grontab
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWorkflows(t *testing.T) {
+	cleaningErr := os.Remove("./db.db")
+	if cleaningErr != nil && os.IsExist(cleaningErr) {
+		t.Errorf("Unable to cleanup test env, before test running")
+	}
+
+	testInit(Config{BucketName: "jobs", PersistencePath: "./db.db", TurnOffLogs: true, HideBanner: true})
+	Start()
+
+	// the jobs run only by the workflow are disabled at their own schedule
+	for id, task := range map[string]string{"extract": "echo 'extract'", "transform": "false", "load": "echo 'load'",
+		"alert": "echo 'alert'", "cleanup": "echo 'cleanup'"} {
+		if _, err := Add("0 0 0 1 1 *", Job{ID: id, Task: task}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	etl := Workflow{
+		Name:     "etl",
+		Schedule: "0 0 2 * * *",
+		Nodes: []WorkflowNode{
+			{JobID: "extract"},
+			{JobID: "transform", DependsOn: []string{"extract"}},
+			{JobID: "load", DependsOn: []string{"transform"}},
+			{JobID: "alert", DependsOn: []string{"transform", "load"}, Trigger: TriggerAnyFailed},
+			{JobID: "cleanup", DependsOn: []string{"load"}, Trigger: TriggerAllDone},
+		},
+	}
+	if err := AddWorkflow(etl); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []Workflow{
+		{Name: "empty"},
+		{Name: "missing", Nodes: []WorkflowNode{{JobID: "nope"}}},
+		{Name: "dangling", Nodes: []WorkflowNode{{JobID: "extract", DependsOn: []string{"load"}}}},
+		{Name: "trigger", Nodes: []WorkflowNode{{JobID: "extract", Trigger: "sometimes"}}},
+		{Name: "cycle", Nodes: []WorkflowNode{
+			{JobID: "extract", DependsOn: []string{"load"}},
+			{JobID: "transform", DependsOn: []string{"extract"}},
+			{JobID: "load", DependsOn: []string{"transform"}},
+		}},
+	}
+	for _, workflow := range invalid {
+		if err := AddWorkflow(workflow); err == nil {
+			t.Errorf("expected AddWorkflow() to refuse the workflow %s", workflow.Name)
+		}
+	}
+	if workflows, _ := Workflows(); len(workflows) != 1 || workflows[0].Name != "etl" {
+		t.Errorf("expected only the etl workflow to be stored, got %v", workflows)
+	}
+
+	// the jobs of a workflow can't be removed
+	if err := Remove("load"); err == nil {
+		t.Errorf("expected Remove() to refuse a job run by a workflow")
+	}
+
+	run, err := RunWorkflow("etl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]RunStatus{
+		"extract":   RunSucceeded,
+		"transform": RunFailed,
+		"load":      RunSkipped,
+		"alert":     RunSucceeded,
+		"cleanup":   RunSucceeded,
+	}
+	if run.Status != RunFailed || len(run.Nodes) != len(expected) {
+		t.Fatalf("expected a failed run with 5 nodes, got %+v", run)
+	}
+	for _, node := range run.Nodes {
+		if node.Status != expected[node.JobID] {
+			t.Errorf("expected node %s to be %s, got %s", node.JobID, expected[node.JobID], node.Status)
+		}
+	}
+	if runs, _ := History("extract"); len(runs) != 1 || runs[0].ID != run.Nodes[0].RunID {
+		t.Errorf("expected the node run in the job history, got %v", runs)
+	}
+	if runs, _ := History("load"); len(runs) != 0 {
+		t.Errorf("expected the skipped node not to run its job, got %v", runs)
+	}
+	if runs, _ := WorkflowHistory("etl"); len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("expected the workflow run to be recorded, got %v", runs)
+	}
+
+	if err := RemoveWorkflow("etl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("load"); err != nil {
+		t.Errorf("expected the job to be removable with its workflow, got %s", err)
+	}
+}